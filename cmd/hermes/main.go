@@ -35,6 +35,16 @@ func main() {
 	}
 
 	globalFlags := parseGlobalFlags()
+	if globalFlags.DryRun && !dryRunCommands[cmd] {
+		fmt.Fprintf(os.Stderr, "--dry-run is not supported for %s (use it with install, serve, run or studio)\n", cmd)
+		os.Exit(2)
+	}
+	switch globalFlags.PlanFormat {
+	case "", "text", "shell", "json":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid --plan-format: %s (use text, shell or json)\n", globalFlags.PlanFormat)
+		os.Exit(2)
+	}
 
 	appCtx, err := app.NewContext(globalFlags)
 	if err != nil {
@@ -45,8 +55,13 @@ func main() {
 
 	cmdArgs := filterGlobalFlags(os.Args[2:])
 
-	if err := dispatch(cmd, appCtx, cmdArgs); err != nil {
+	err = dispatch(cmd, appCtx, cmdArgs)
+	if planErr := appCtx.WritePlan(); planErr != nil {
+		appCtx.Logger.Error("failed to write plan", "error", planErr)
+	}
+	if err != nil {
 		appCtx.Logger.Error("command failed", "cmd", cmd, "error", err)
+		appCtx.Close()
 		os.Exit(1)
 	}
 }
@@ -67,6 +82,13 @@ func parseGlobalFlags() app.GlobalFlags {
 			flags.NoColor = true
 		case "--force-color":
 			flags.ForceColor = true
+		case "--dry-run":
+			flags.DryRun = true
+		case "--plan-format":
+			if i+1 < len(os.Args) {
+				flags.PlanFormat = os.Args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -79,37 +101,42 @@ func parseGlobalFlags() app.GlobalFlags {
 
 func filterGlobalFlags(args []string) []string {
 	var filtered []string
-	skip := false
-	for i, arg := range args {
-		if skip {
-			skip = false
-			continue
-		}
-		switch arg {
-		case "--log-file":
-			skip = true
-			continue
-		case "--debug", "--no-color", "--force-color":
-			continue
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--log-file", "--plan-format":
+			i++
+		case "--debug", "--no-color", "--force-color", "--dry-run":
 		default:
-			if i > 0 && args[i-1] == "--log-file" {
-				continue
-			}
-			filtered = append(filtered, arg)
+			filtered = append(filtered, args[i])
 		}
 	}
 	return filtered
 }
 
+// dryRunCommands are the commands that can plan instead of act.
+var dryRunCommands = map[string]bool{
+	"install": true,
+	"serve":   true,
+	"run":     true,
+	"studio":  true,
+}
+
 type CommandFunc func(ctx *app.AppContext, args []string) error
 
 var commandRegistry = map[string]CommandFunc{
-	"doctor":  commands.Doctor,
-	"install": commands.Install,
-	"serve":   commands.Serve,
-	"verify":  commands.Verify,
-	"studio":  commands.Studio,
-	"run":     commands.Run,
+	"doctor":    commands.Doctor,
+	"install":   commands.Install,
+	"serve":     commands.Serve,
+	"verify":    commands.Verify,
+	"studio":    commands.Studio,
+	"run":       commands.Run,
+	"engines":   commands.Engines,
+	"uninstall": commands.Uninstall,
+	"upgrade":   commands.Upgrade,
+	"ps":        commands.Ps,
+	"stop":      commands.Stop,
+	"logs":      commands.Logs,
+	"export":    commands.Export,
 }
 
 func dispatch(cmd string, ctx *app.AppContext, args []string) error {
@@ -117,6 +144,8 @@ func dispatch(cmd string, ctx *app.AppContext, args []string) error {
 	if !ok {
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", cmd)
 		printUsage()
+		// os.Exit skips main's deferred Close.
+		ctx.Close()
 		os.Exit(2)
 	}
 	return handler(ctx, args)
@@ -131,18 +160,25 @@ func printVersion() {
 func printUsage() {
 	fmt.Print(ui.Banner())
 	fmt.Println()
-	fmt.Println("GPU inference server launcher for sglang and vllm")
+	fmt.Println("GPU inference server launcher for sglang, vllm and lmdeploy")
 	fmt.Println()
 	fmt.Println("Usage:")
 	fmt.Println("  hermes <command> [flags]")
 	fmt.Println()
 	fmt.Println("Commands:")
 	fmt.Println("  doctor    Check GPU, CUDA, and system requirements")
-	fmt.Println("  install   Install inference engines (sglang, vllm)")
+	fmt.Println("  install   Install inference engines (sglang, vllm, lmdeploy)")
+	fmt.Println("  upgrade   Upgrade an installed engine")
+	fmt.Println("  uninstall Remove an installed engine")
 	fmt.Println("  serve     Start inference server")
+	fmt.Println("  ps        List servers started by hermes")
+	fmt.Println("  stop      Stop a server started by hermes")
+	fmt.Println("  logs      Show a server's output")
 	fmt.Println("  verify    Verify server is responding")
 	fmt.Println("  studio    Launch vllm-studio controller")
 	fmt.Println("  run       Run full pipeline (doctor → install → serve → verify)")
+	fmt.Println("  engines   List supported engines and their capabilities")
+	fmt.Println("  export    Render a standalone launch script, Dockerfile or compose file")
 	fmt.Println("  version   Show version information")
 	fmt.Println("  help      Show this help message")
 	fmt.Println()
@@ -153,14 +189,20 @@ func printUsage() {
 	fs.Bool("debug", false, "Enable debug logging")
 	fs.Bool("no-color", false, "Disable colored output")
 	fs.Bool("force-color", false, "Force colored output")
+	fs.Bool("dry-run", false, "Print what install, serve, run or studio would execute without doing it")
+	fs.String("plan-format", "text", "Dry-run plan format: text|shell|json")
 	fs.PrintDefaults()
 
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  hermes doctor --json")
+	fmt.Println("  hermes doctor --only gpu")
+	fmt.Println("  hermes doctor --fix")
 	fmt.Println("  hermes install --install sglang")
 	fmt.Println("  hermes serve --engine vllm --model meta-llama/Llama-3-8B --tp 4")
 	fmt.Println("  hermes run --engine sglang --model mymodel --daemon")
+	fmt.Println("  hermes serve --engine vllm --model mymodel --runtime docker --daemon")
+	fmt.Println("  hermes export --format compose --engine vllm --model mymodel -o compose.yaml")
 	fmt.Println()
 	fmt.Println("For command-specific help:")
 	fmt.Println("  hermes <command> --help")
@@ -3,22 +3,33 @@ package app
 import (
 	"context"
 	"io"
+	"net/http"
 	"os"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/log"
+
+	"github.com/svngoku/hermes-cli/internal/execx"
 )
 
 type AppContext struct {
-	Ctx       context.Context
-	Cancel    context.CancelFunc
-	Logger    *log.Logger
-	Stdout    io.Writer
-	Stderr    io.Writer
-	Debug     bool
-	NoColor   bool
-	LogFile   string
+	Ctx     context.Context
+	Cancel  context.CancelFunc
+	Logger  *log.Logger
+	Stdout  io.Writer
+	Stderr  io.Writer
+	Debug   bool
+	NoColor bool
+	LogFile string
+	Runner  execx.Runner
+
+	// DryRun commands only plan external commands and server launches; the
+	// plan is written to standard output in PlanFormat when they finish.
+	DryRun     bool
+	PlanFormat string
+
 	logWriter io.Writer
+	logSink   *os.File
 }
 
 type GlobalFlags struct {
@@ -26,12 +37,15 @@ type GlobalFlags struct {
 	Debug      bool
 	NoColor    bool
 	ForceColor bool
+	DryRun     bool
+	PlanFormat string
 }
 
 func NewContext(flags GlobalFlags) (*AppContext, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	var logWriter io.Writer = os.Stderr
+	var logSink *os.File
 	if flags.LogFile != "" {
 		f, err := os.OpenFile(flags.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 		if err != nil {
@@ -39,6 +53,7 @@ func NewContext(flags GlobalFlags) (*AppContext, error) {
 			return nil, err
 		}
 		logWriter = io.MultiWriter(os.Stderr, f)
+		logSink = f
 	}
 
 	logger := log.NewWithOptions(logWriter, log.Options{
@@ -60,22 +75,114 @@ func NewContext(flags GlobalFlags) (*AppContext, error) {
 		lipgloss.SetHasDarkBackground(true)
 	}
 
+	runner, err := newRunner(logger)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stdout := io.Writer(os.Stdout)
+	if flags.DryRun {
+		runner = execx.NewPlanner(runner)
+		// Keep standard output for the plan itself.
+		stdout = os.Stderr
+	}
+	ctx = execx.WithRunner(ctx, runner)
+
 	return &AppContext{
-		Ctx:       ctx,
-		Cancel:    cancel,
-		Logger:    logger,
-		Stdout:    os.Stdout,
-		Stderr:    os.Stderr,
-		Debug:     flags.Debug,
-		NoColor:   flags.NoColor,
-		LogFile:   flags.LogFile,
-		logWriter: logWriter,
+		Ctx:        ctx,
+		Cancel:     cancel,
+		Logger:     logger,
+		Stdout:     stdout,
+		Stderr:     os.Stderr,
+		Debug:      flags.Debug,
+		NoColor:    flags.NoColor,
+		LogFile:    flags.LogFile,
+		Runner:     runner,
+		DryRun:     flags.DryRun,
+		PlanFormat: flags.PlanFormat,
+		logWriter:  logWriter,
+		logSink:    logSink,
 	}, nil
 }
 
+// LogSink returns the log file alone, for raw subprocess output that should
+// be kept but not shown on the terminal.
+func (a *AppContext) LogSink() io.Writer {
+	if a.logSink == nil {
+		return io.Discard
+	}
+	return a.logSink
+}
+
+// WithContext returns a shallow copy of a whose commands run under ctx, for
+// work with its own deadline. Only the original should be closed, since it
+// owns the log file and cassette.
+func (a *AppContext) WithContext(ctx context.Context) *AppContext {
+	c := *a
+	c.Ctx = ctx
+	return &c
+}
+
+// newRunner picks how external commands run: replayed from the cassette in
+// HERMES_REPLAY, recorded to the one in HERMES_RECORD, or just run.
+func newRunner(logger *log.Logger) (execx.Runner, error) {
+	if path := os.Getenv("HERMES_REPLAY"); path != "" {
+		cassette, err := execx.LoadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		logger.Debug("replaying commands", "cassette", path)
+		return execx.NewReplayer(cassette), nil
+	}
+	if path := os.Getenv("HERMES_RECORD"); path != "" {
+		logger.Debug("recording commands", "cassette", path)
+		return execx.NewRecorder(execx.LocalRunner{}, path), nil
+	}
+	return execx.LocalRunner{}, nil
+}
+
+// HTTPClient returns the client for lookups that should be recorded and
+// replayed along with the commands, such as the HuggingFace hub API.
+func (a *AppContext) HTTPClient() *http.Client {
+	if rt, ok := a.Runner.(http.RoundTripper); ok {
+		return &http.Client{Transport: rt}
+	}
+	return http.DefaultClient
+}
+
+// Plan returns the dry-run plan, or nil outside a dry run.
+func (a *AppContext) Plan() *execx.Plan {
+	if p, ok := a.Runner.(*execx.Planner); ok {
+		return p.Plan()
+	}
+	return nil
+}
+
+// WritePlan prints the dry-run plan to standard output.
+func (a *AppContext) WritePlan() error {
+	plan := a.Plan()
+	if plan == nil {
+		return nil
+	}
+	switch a.PlanFormat {
+	case "json":
+		return plan.WriteJSON(os.Stdout)
+	case "shell":
+		plan.WriteShell(os.Stdout)
+	default:
+		plan.WriteText(os.Stdout)
+	}
+	return nil
+}
+
 func (a *AppContext) Close() {
 	a.Cancel()
-	if closer, ok := a.logWriter.(io.Closer); ok && a.logWriter != os.Stderr {
-		closer.Close()
+	if rec, ok := a.Runner.(*execx.Recorder); ok {
+		if err := rec.Save(); err != nil {
+			a.Logger.Error("failed to save cassette", "error", err)
+		}
+	}
+	if a.logSink != nil {
+		a.logSink.Close()
 	}
 }
@@ -0,0 +1,273 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/engine"
+	"github.com/svngoku/hermes-cli/internal/ui"
+)
+
+const bundleManifestName = "manifest.json"
+
+// BundleManifest describes a wheelhouse produced by `hermes install bundle`.
+// File paths are relative to the bundle directory.
+type BundleManifest struct {
+	CreatedAt     time.Time         `json:"created_at"`
+	Platform      string            `json:"platform"`
+	PythonVersion string            `json:"python_version,omitempty"`
+	Requirements  map[string]string `json:"requirements"`
+	Files         []BundleFile      `json:"files"`
+}
+
+type BundleFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// wheelDir is where wheels live inside a bundle. A plain directory of wheels
+// without a manifest is accepted as a wheelhouse too.
+func wheelDir(bundle string) string {
+	if info, err := os.Stat(filepath.Join(bundle, "wheels")); err == nil && info.IsDir() {
+		return filepath.Join(bundle, "wheels")
+	}
+	return bundle
+}
+
+// bundledUV returns the uv binary shipped in a bundle, if any.
+func bundledUV(bundle string) string {
+	path := filepath.Join(bundle, "bin", "uv")
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	return ""
+}
+
+// InstallBundle assembles a portable wheelhouse on a connected machine: the
+// engine wheels and their dependencies, the uv binary, and a manifest with
+// checksums that `hermes install --wheelhouse` verifies before installing.
+func InstallBundle(ctx *app.AppContext, args []string) error {
+	fs := flag.NewFlagSet("install bundle", flag.ExitOnError)
+	out := fs.String("out", "hermes-bundle", "Output directory")
+	installMode := fs.String("install", "both", "Engines to bundle: sglang|vllm|lmdeploy|both")
+	pythonVersion := fs.String("python", "", "Python version of the target hosts (default: uv's default)")
+	versions := map[config.Engine]*string{
+		config.EngineSGLang:   fs.String("sglang-version", "", "sglang version or specifier"),
+		config.EngineVLLM:     fs.String("vllm-version", "", "vllm version or specifier"),
+		config.EngineLMDeploy: fs.String("lmdeploy-version", "", "lmdeploy version or specifier"),
+	}
+	var indexURLs, constraints, extras stringList
+	fs.Var(&indexURLs, "extra-index-url", "Extra package index URL (repeatable)")
+	fs.Var(&constraints, "constraint", "pip constraints file (repeatable)")
+	fs.Var(&extras, "extras", "Engine extras as engine=extra[,extra] (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(ctx.Stdout, "Usage: hermes install bundle [flags]")
+		fmt.Fprintln(ctx.Stdout)
+		fmt.Fprintln(ctx.Stdout, "Assemble an offline wheelhouse for hermes install --wheelhouse")
+		fmt.Fprintln(ctx.Stdout)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if ctx.DryRun {
+		return fmt.Errorf("--dry-run is not supported for install bundle")
+	}
+
+	engines := modeEngines(config.InstallMode(*installMode))
+	if len(engines) == 0 {
+		return fmt.Errorf("invalid install mode for bundle: %s", *installMode)
+	}
+	engineExtras, err := parseExtras(extras)
+	if err != nil {
+		return err
+	}
+	if !ctx.Runner.CommandExists("uv") {
+		return fmt.Errorf("uv is required to build a bundle")
+	}
+
+	root, err := filepath.Abs(*out)
+	if err != nil {
+		return err
+	}
+	wheels := filepath.Join(root, "wheels")
+	if err := os.MkdirAll(wheels, 0755); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(ctx.Stdout, ui.Banner())
+	fmt.Fprintln(ctx.Stdout, ui.Step(fmt.Sprintf("Building bundle in %s...", root)))
+	fmt.Fprintln(ctx.Stdout, ui.HR())
+
+	// pip download resolves for the interpreter it runs under, so use a
+	// throwaway seeded venv on the requested Python version.
+	scratch, err := os.MkdirTemp("", "hermes-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratch)
+
+	venvArgs := []string{"venv", "--seed", scratch}
+	if *pythonVersion != "" {
+		venvArgs = append(venvArgs, "--python", *pythonVersion)
+	}
+	if result := ctx.Runner.Run(ctx.Ctx, "uv", venvArgs...); result.ExitCode != 0 {
+		return fmt.Errorf("failed to create scratch venv: %s", result.Stderr)
+	}
+	scratchEnv := engine.Env{Path: scratch}
+
+	manifest := BundleManifest{
+		CreatedAt:    time.Now().UTC(),
+		Platform:     runtime.GOOS + "/" + runtime.GOARCH,
+		Requirements: make(map[string]string),
+	}
+	if result := ctx.Runner.Run(ctx.Ctx, scratchEnv.Python(), "-c", "import sys; print(sys.version.split()[0])"); result.ExitCode == 0 {
+		manifest.PythonVersion = result.Stdout
+	}
+
+	for _, name := range engines {
+		opts := engine.InstallOptions{
+			Version: *versions[name],
+			Extras:  engineExtras[string(name)],
+		}
+		req := engine.RequirementFor(name, opts)
+		fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Downloading %s and dependencies...", req)))
+
+		dlArgs := []string{"-m", "pip", "download", "--prefer-binary", "--dest", wheels}
+		for _, url := range indexURLs {
+			dlArgs = append(dlArgs, "--extra-index-url", url)
+		}
+		for _, c := range constraints {
+			dlArgs = append(dlArgs, "--constraint", c)
+		}
+		dlArgs = append(dlArgs, req)
+		if result := ctx.Runner.Run(ctx.Ctx, scratchEnv.Python(), dlArgs...); result.ExitCode != 0 {
+			fmt.Fprintln(ctx.Stdout, ui.Fail(fmt.Sprintf("download of %s failed", req)))
+			return fmt.Errorf("failed to download %s: %s", req, result.Stderr)
+		}
+		manifest.Requirements[string(name)] = req
+		fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("%s downloaded", req)))
+	}
+
+	uvPath, err := ctx.Runner.LookPath("uv")
+	if err != nil {
+		return err
+	}
+	if err := copyFile(uvPath, filepath.Join(root, "bin", "uv"), 0755); err != nil {
+		return fmt.Errorf("failed to copy uv binary: %w", err)
+	}
+	fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("uv binary copied from %s", uvPath)))
+
+	files, err := checksumTree(root)
+	if err != nil {
+		return err
+	}
+	manifest.Files = files
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(root, bundleManifestName), data, 0644); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(ctx.Stdout, ui.HR())
+	fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("Bundle ready: %s (%d files)", root, len(files))))
+	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("On the target host: hermes install --install %s --wheelhouse %s", *installMode, root)))
+	return nil
+}
+
+// verifyBundle checks every file listed in the bundle manifest. A directory
+// without a manifest is returned as nil with no error.
+func verifyBundle(bundle string) (*BundleManifest, error) {
+	data, err := os.ReadFile(filepath.Join(bundle, bundleManifestName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	for _, f := range manifest.Files {
+		sum, _, err := fileSHA256(filepath.Join(bundle, f.Path))
+		if err != nil {
+			return nil, fmt.Errorf("bundle file %s: %w", f.Path, err)
+		}
+		if sum != f.SHA256 {
+			return nil, fmt.Errorf("bundle file %s: checksum mismatch", f.Path)
+		}
+	}
+	return &manifest, nil
+}
+
+// checksumTree hashes every regular file under root except the manifest.
+func checksumTree(root string) ([]BundleFile, error) {
+	var files []BundleFile
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == bundleManifestName {
+			return err
+		}
+		sum, size, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, BundleFile{Path: filepath.ToSlash(rel), Size: size, SHA256: sum})
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, err
+}
+
+func fileSHA256(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,548 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/engine"
+	"github.com/svngoku/hermes-cli/internal/execx"
+	"github.com/svngoku/hermes-cli/internal/gpu"
+	"github.com/svngoku/hermes-cli/internal/ui"
+	"github.com/svngoku/hermes-cli/internal/ui/tui"
+)
+
+// Severity is how much a failing check matters to serving.
+type Severity string
+
+const (
+	// SeverityRequired checks must pass for an engine to serve; the run
+	// pipeline stops when one fails.
+	SeverityRequired    Severity = "required"
+	SeverityRecommended Severity = "recommended"
+	SeverityOptional    Severity = "optional"
+)
+
+// Check is one entry in the doctor registry. Run reports its own status;
+// Remediation is shown when that status is a warning or failure, unless the
+// result carries a more specific one.
+type Check struct {
+	ID          string
+	Category    string
+	Severity    Severity
+	Description string
+	// DependsOn lists checks that must pass (or warn) for this one to be
+	// meaningful. A dependency that was not run does not block it.
+	DependsOn   []string
+	Remediation string
+	// Timeout bounds Run; zero means defaultCheckTimeout.
+	Timeout time.Duration
+	Run     func(ctx *app.AppContext, run *checkRun) CheckResult
+	// Fix, if set, is asked by doctor --fix for a remediation of a result
+	// that did not pass; it returns nil when it has none to offer.
+	Fix func(ctx *app.AppContext, run *checkRun, result CheckResult) *fixAction
+}
+
+// defaultCheckTimeout leaves room for a probe that hits execx.ProbeTimeout
+// and the grace period for its output to drain.
+const defaultCheckTimeout = 30 * time.Second
+
+// checkRun holds what checks share within one doctor invocation.
+type checkRun struct {
+	mu        sync.Mutex
+	engines   map[string]engine.EnvInfo
+	inventory *gpu.Inventory
+	topology  *gpu.Topology
+	tpGroup   *TPGroup
+	// model is the model doctor was asked about, if any.
+	model string
+	// tp is the tensor-parallel size the checks are sized for.
+	tp int
+	// only holds the selectors given to --only.
+	only []string
+}
+
+func newCheckRun() *checkRun {
+	return &checkRun{engines: make(map[string]engine.EnvInfo), tp: 1}
+}
+
+func (r *checkRun) setEngine(info engine.EnvInfo) {
+	r.mu.Lock()
+	r.engines[info.Name] = info
+	r.mu.Unlock()
+}
+
+func (r *checkRun) setInventory(inv *gpu.Inventory) {
+	r.mu.Lock()
+	r.inventory = inv
+	r.mu.Unlock()
+}
+
+func (r *checkRun) setTopology(topo *gpu.Topology) {
+	r.mu.Lock()
+	r.topology = topo
+	r.mu.Unlock()
+}
+
+func (r *checkRun) setTPGroup(group *TPGroup) {
+	r.mu.Lock()
+	r.tpGroup = group
+	r.mu.Unlock()
+}
+
+// requested reports whether id was named with --only.
+func (r *checkRun) requested(id string) bool {
+	for _, s := range r.only {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
+
+// gpuInventory returns what the nvidia-smi check found, or nil if it did not
+// run or failed.
+func (r *checkRun) gpuInventory() *gpu.Inventory {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.inventory
+}
+
+// engine returns what the engine's check found, if it ran.
+func (r *checkRun) engine(name string) (engine.EnvInfo, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info, ok := r.engines[name]
+	return info, ok
+}
+
+// engineInfos returns the inspected engines in display order.
+func (r *checkRun) engineInfos() []engine.EnvInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var infos []engine.EnvInfo
+	for _, name := range engine.Names() {
+		if info, ok := r.engines[string(name)]; ok {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// doctorChecks is the registry, in the order results are reported. Engine
+// checks are generated for every supported engine.
+func doctorChecks() []Check {
+	checks := []Check{
+		{
+			ID:          "nvidia-smi",
+			Category:    "gpu",
+			Severity:    SeverityRequired,
+			Description: "NVIDIA driver responds to nvidia-smi",
+			Remediation: "Install the NVIDIA driver and put nvidia-smi on PATH; in a container, start it with --gpus all (NVIDIA Container Toolkit)",
+			Run:         checkNvidiaSMI,
+		},
+		{
+			ID:          "gpu_count",
+			Category:    "gpu",
+			Severity:    SeverityRequired,
+			Description: "At least one GPU is visible and free of uncorrectable ECC errors",
+			DependsOn:   []string{"nvidia-smi"},
+			Remediation: "Check CUDA_VISIBLE_DEVICES, the container's --gpus option, and driver errors in dmesg",
+			Run:         checkGPUs,
+		},
+		{
+			ID:          "topology",
+			Category:    "gpu",
+			Severity:    SeverityOptional,
+			Description: "NVLink islands, NUMA affinity and the best GPUs for --tp",
+			DependsOn:   []string{"nvidia-smi"},
+			Run:         checkTopology,
+		},
+		{
+			ID:          "cuda",
+			Category:    "toolchain",
+			Severity:    SeverityOptional,
+			Description: "CUDA toolkit (nvcc) version",
+			Remediation: "Only needed to build kernels from source; engine wheels ship their own CUDA runtime",
+			Run:         checkCUDA,
+		},
+		{
+			ID:          "uv",
+			Category:    "toolchain",
+			Severity:    SeverityRecommended,
+			Description: "uv package manager is installed",
+			Remediation: "hermes install downloads uv automatically, or see https://docs.astral.sh/uv/",
+			Run:         checkUV,
+			Fix:         fixUV,
+		},
+		{
+			ID:          "python",
+			Category:    "toolchain",
+			Severity:    SeverityRecommended,
+			Description: "A system Python interpreter is available",
+			Remediation: "Install Python 3.10 or later, or let uv provide one",
+			Run:         checkPython,
+		},
+		{
+			ID:          "disk:hf_cache",
+			Category:    "disk",
+			Severity:    SeverityRecommended,
+			Description: "HuggingFace cache has room for models (and for --model's download)",
+			Remediation: "Free space, or point HF_HOME (or HF_HUB_CACHE) at a larger volume",
+			Timeout:     45 * time.Second,
+			Run:         checkCacheDisk,
+			Fix:         fixCacheDir,
+		},
+		{
+			ID:          "disk:envs",
+			Category:    "disk",
+			Severity:    SeverityRecommended,
+			Description: "Engine environment directory has room for an install",
+			Remediation: "Free space, or install onto a larger volume with hermes install --env-root",
+			Run:         checkEnvDisk,
+		},
+		{
+			ID:          "disk:logs",
+			Category:    "disk",
+			Severity:    SeverityOptional,
+			Description: "Log and state directories have free space",
+			Remediation: "Free space, or write logs elsewhere with --log-file",
+			Run:         checkLogDisk,
+		},
+		{
+			ID:          "shm",
+			Category:    "system",
+			Severity:    SeverityRecommended,
+			Description: "/dev/shm is large enough for NCCL at the requested TP size",
+			Run:         checkShm,
+			Fix:         fixShm,
+		},
+		{
+			ID:          "memlock",
+			Category:    "system",
+			Severity:    SeverityRecommended,
+			Description: "Locked-memory limit allows NCCL to pin buffers",
+			Run:         checkMemlock,
+			Fix:         fixMemlock,
+		},
+		{
+			ID:          "nofile",
+			Category:    "system",
+			Severity:    SeverityRecommended,
+			Description: "Open-file limit suits the requested TP size",
+			Run:         checkNoFile,
+			Fix:         fixNoFile,
+		},
+		{
+			ID:          "nccl",
+			Category:    "system",
+			Severity:    SeverityOptional,
+			Description: "NCCL environment does not disable fast transports",
+			Run:         checkNCCL,
+		},
+	}
+	for _, name := range engine.Names() {
+		name := name
+		checks = append(checks, Check{
+			ID:          "engine:" + string(name),
+			Category:    "engine",
+			Severity:    SeverityRecommended,
+			Description: fmt.Sprintf("%s environment imports and sees CUDA", name),
+			Remediation: fmt.Sprintf("Reinstall with: hermes install --install %s --upgrade", name),
+			Timeout:     2 * execx.ImportTimeout,
+			Run: func(ctx *app.AppContext, run *checkRun) CheckResult {
+				return checkEngineEnv(ctx, run, name)
+			},
+			Fix: fixEngine(name),
+		})
+	}
+	for _, name := range engine.Names() {
+		name := name
+		checks = append(checks, Check{
+			ID:          "compat:" + string(name),
+			Category:    "compat",
+			Severity:    SeverityRecommended,
+			Description: fmt.Sprintf("Driver CUDA and GPU architecture suit the %s environment's torch", name),
+			DependsOn:   []string{"nvidia-smi", "engine:" + string(name)},
+			Remediation: "Upgrade the NVIDIA driver, or reinstall with a torch build for an older CUDA via --extra-index-url",
+			Run: func(ctx *app.AppContext, run *checkRun) CheckResult {
+				return checkCompat(ctx, run, name)
+			},
+		})
+	}
+	return checks
+}
+
+// selectChecks filters the registry. Selectors match a check ID or a
+// category. Checks that --only selects bring their dependencies with them.
+func selectChecks(all []Check, only, skip []string) ([]Check, error) {
+	byID := make(map[string]Check)
+	categories := make(map[string]bool)
+	for _, c := range all {
+		byID[c.ID] = c
+		categories[c.Category] = true
+	}
+	matches := func(c Check, selectors []string) bool {
+		for _, s := range selectors {
+			if s == c.ID || s == c.Category {
+				return true
+			}
+		}
+		return false
+	}
+	for _, s := range append(append([]string{}, only...), skip...) {
+		if _, ok := byID[s]; !ok && !categories[s] {
+			return nil, fmt.Errorf("unknown check %q (see 'hermes doctor --list')", s)
+		}
+	}
+
+	selected := make(map[string]bool)
+	var include func(id string)
+	include = func(id string) {
+		if selected[id] {
+			return
+		}
+		selected[id] = true
+		for _, dep := range byID[id].DependsOn {
+			include(dep)
+		}
+	}
+	for _, c := range all {
+		if len(only) == 0 || matches(c, only) {
+			include(c.ID)
+		}
+	}
+
+	var checks []Check
+	for _, c := range all {
+		if selected[c.ID] && !matches(c, skip) {
+			checks = append(checks, c)
+		}
+	}
+	return checks, nil
+}
+
+// checksByID selects exactly the given checks, for callers such as the run
+// pipeline that need a fixed set.
+func checksByID(ids ...string) []Check {
+	checks, err := selectChecks(doctorChecks(), ids, nil)
+	if err != nil {
+		panic(err)
+	}
+	return checks
+}
+
+// checkObserver is told when the check at index starts (result is nil) and
+// when it finishes.
+type checkObserver func(index int, result *CheckResult)
+
+// runChecks runs checks concurrently, each once its dependencies have
+// finished, and returns the results in the order of checks. A check whose
+// dependency ran and did not pass is skipped rather than reporting a second,
+// derived failure.
+func runChecks(ctx *app.AppContext, run *checkRun, checks []Check, observe checkObserver) []CheckResult {
+	results := make([]CheckResult, len(checks))
+	index := make(map[string]int, len(checks))
+	done := make([]chan struct{}, len(checks))
+	for i, c := range checks {
+		index[c.ID] = i
+		done[i] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Check) {
+			defer wg.Done()
+			defer close(done[i])
+
+			var failed string
+			for _, dep := range c.DependsOn {
+				j, ran := index[dep]
+				if !ran {
+					continue
+				}
+				<-done[j]
+				if s := results[j].Status; s != StatusOK && s != StatusWarning && failed == "" {
+					failed = dep
+				}
+			}
+			if observe != nil {
+				observe(i, nil)
+			}
+			if failed != "" {
+				results[i] = c.result(CheckResult{Status: StatusSkipped, Message: fmt.Sprintf("%s skipped: %s did not pass", c.ID, failed)})
+			} else {
+				results[i] = runCheck(ctx, run, c)
+			}
+			if observe != nil {
+				observe(i, &results[i])
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
+
+// runCheck runs one check under its timeout. A check that overruns is
+// abandoned: its commands are killed by the expired context, and whatever
+// it returns later is discarded.
+func runCheck(ctx *app.AppContext, run *checkRun, c Check) CheckResult {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultCheckTimeout
+	}
+	checkCtx, cancel := execx.WithTimeout(ctx.Ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	ch := make(chan CheckResult, 1)
+	go func() {
+		ch <- c.Run(ctx.WithContext(checkCtx), run)
+	}()
+
+	var r CheckResult
+	select {
+	case r = <-ch:
+	case <-checkCtx.Done():
+		r = CheckResult{Status: StatusWarning, Message: fmt.Sprintf("%s did not finish within %s", c.ID, timeout)}
+		if c.Severity == SeverityRequired {
+			r.Status = StatusFail
+		}
+	}
+	r.DurationMs = time.Since(start).Milliseconds()
+	return c.result(r)
+}
+
+// displayChecks runs checks and prints their results in registry order. On
+// a terminal, checks in flight are shown as a live list first; otherwise
+// each result is printed as soon as those before it have finished.
+func displayChecks(ctx *app.AppContext, run *checkRun, checks []Check) []CheckResult {
+	if ui.IsTerminal(ctx.Stdout) {
+		results := runChecksWithSpinners(ctx, run, checks)
+		for _, r := range results {
+			printCheckResult(ctx, r)
+		}
+		return results
+	}
+
+	var mu sync.Mutex
+	finished := make([]*CheckResult, len(checks))
+	next := 0
+	return runChecks(ctx, run, checks, func(i int, r *CheckResult) {
+		if r == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		finished[i] = r
+		for next < len(finished) && finished[next] != nil {
+			printCheckResult(ctx, *finished[next])
+			next++
+		}
+	})
+}
+
+func runChecksWithSpinners(ctx *app.AppContext, run *checkRun, checks []Check) []CheckResult {
+	names := make([]string, len(checks))
+	for i, c := range checks {
+		names[i] = c.ID
+	}
+	model := tui.NewStepsModel(names).WithTitle("Doctor checks").Transient()
+	program := tea.NewProgram(model, tea.WithOutput(ctx.Stdout))
+
+	// Quitting cancels only the checks, not the rest of the command.
+	checkCtx, cancel := context.WithCancel(ctx.Ctx)
+	defer cancel()
+
+	resultsCh := make(chan []CheckResult, 1)
+	go func() {
+		resultsCh <- runChecks(ctx.WithContext(checkCtx), run, checks, func(i int, r *CheckResult) {
+			msg := tui.StepUpdateMsg{Index: i, Status: tui.StepRunning}
+			if r != nil {
+				msg.Status = checkStepStatus(r.Status)
+				msg.Detail = (time.Duration(r.DurationMs) * time.Millisecond).String()
+			}
+			program.Send(msg)
+		})
+		program.Send(tui.AllDoneMsg{})
+	}()
+
+	program.Run()
+	select {
+	case results := <-resultsCh:
+		return results
+	default:
+		// The user quit before the checks finished; cancel those in flight.
+		cancel()
+		return <-resultsCh
+	}
+}
+
+func checkStepStatus(s CheckStatus) tui.StepStatus {
+	switch s {
+	case StatusOK:
+		return tui.StepDone
+	case StatusFail:
+		return tui.StepFailed
+	default:
+		return tui.StepSkipped
+	}
+}
+
+// result fills in the registry's metadata on what Run returned.
+func (c Check) result(r CheckResult) CheckResult {
+	r.Name = c.ID
+	r.Category = c.Category
+	r.Severity = c.Severity
+	if r.Remediation == "" && (r.Status == StatusWarning || r.Status == StatusFail) {
+		r.Remediation = c.Remediation
+	}
+	return r
+}
+
+// maxDetailLines bounds how much of a failure's output doctor prints; the
+// JSON report keeps all of it.
+const maxDetailLines = 5
+
+func printCheckResult(ctx *app.AppContext, r CheckResult) {
+	switch r.Status {
+	case StatusOK:
+		fmt.Fprintln(ctx.Stdout, ui.Ok(r.Message))
+	case StatusWarning:
+		fmt.Fprintln(ctx.Stdout, ui.Warn(r.Message))
+	case StatusFail:
+		fmt.Fprintln(ctx.Stdout, ui.Fail(r.Message))
+	default:
+		fmt.Fprintln(ctx.Stdout, ui.Info(r.Message))
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(r.Details), "\n")
+	if len(lines) > maxDetailLines {
+		lines = lines[len(lines)-maxDetailLines:]
+	}
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			fmt.Fprintln(ctx.Stdout, "    "+line)
+		}
+	}
+	if r.Remediation != "" {
+		fmt.Fprintln(ctx.Stdout, "    → "+r.Remediation)
+	}
+}
+
+func printCheckList(ctx *app.AppContext, checks []Check) {
+	rows := [][]string{{"ID", "CATEGORY", "SEVERITY", "DEPENDS ON", "DESCRIPTION"}}
+	for _, c := range checks {
+		deps := append([]string(nil), c.DependsOn...)
+		sort.Strings(deps)
+		rows = append(rows, []string{c.ID, c.Category, string(c.Severity), orDash(strings.Join(deps, ",")), c.Description})
+	}
+	printTable(ctx, rows)
+}
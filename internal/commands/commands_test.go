@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+)
+
+// isolateHome points hermes' state, engine environments and the HF cache at
+// temporary directories and clears the variables that change how commands
+// run.
+func isolateHome(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("HF_HOME", filepath.Join(t.TempDir(), "hf"))
+	for _, name := range []string{"HF_TOKEN", "HUGGING_FACE_HUB_TOKEN", "HF_ENDPOINT", "HF_HUB_OFFLINE", "HERMES_RECORD", "HERMES_REPLAY"} {
+		unsetenv(t, name)
+	}
+}
+
+func unsetenv(t *testing.T, name string) {
+	t.Helper()
+	if old, ok := os.LookupEnv(name); ok {
+		os.Unsetenv(name)
+		t.Cleanup(func() { os.Setenv(name, old) })
+	}
+}
+
+// fakeCommand installs a shell script as name in a directory put first on
+// PATH.
+func fakeCommand(t *testing.T, name, script string) {
+	t.Helper()
+	bin := t.TempDir()
+	writeScript(t, filepath.Join(bin, name), script)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func writeScript(t *testing.T, path, script string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeEngineEnv creates the managed environment of engine with an
+// interpreter that reports version as installed.
+func fakeEngineEnv(t *testing.T, engine, version string) {
+	t.Helper()
+	home, _ := os.UserHomeDir()
+	python := filepath.Join(home, ".cache", "hermes", "envs", engine, "bin", "python")
+	writeScript(t, python, "#!/bin/sh\necho '{\"version\": \""+version+"\"}'\n")
+}
+
+func newTestContext(t *testing.T) (*app.AppContext, *bytes.Buffer) {
+	t.Helper()
+	return newTestContextWith(t, app.GlobalFlags{NoColor: true})
+}
+
+func newTestContextWith(t *testing.T, flags app.GlobalFlags) (*app.AppContext, *bytes.Buffer) {
+	t.Helper()
+	ctx, err := app.NewContext(flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ctx.Close)
+	out := &bytes.Buffer{}
+	ctx.Stdout = out
+	return ctx, out
+}
@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/engine"
+)
+
+// torchCUDAIndexes are the CUDA builds published on download.pytorch.org,
+// oldest first.
+var torchCUDAIndexes = []string{"11.8", "12.1", "12.4", "12.6", "12.8"}
+
+// checkCompat compares what an engine's environment was built for with what
+// the host provides: the torch CUDA version against the newest CUDA the
+// driver supports, and each GPU's compute capability against the engine's
+// minimum and the architectures torch was compiled for.
+func checkCompat(ctx *app.AppContext, run *checkRun, name config.Engine) CheckResult {
+	var check CheckResult
+
+	inv := run.gpuInventory()
+	info, ok := run.engine(string(name))
+	if inv == nil || !ok || !info.Installed {
+		check.Status = StatusSkipped
+		check.Message = fmt.Sprintf("%s compatibility not checked", name)
+		return check
+	}
+
+	var failures, warnings []string
+	switch torchCUDA := majorMinor(info.TorchCUDA); {
+	case info.TorchVersion == "":
+		warnings = append(warnings, fmt.Sprintf("torch is not importable in the %s environment", name))
+	case torchCUDA == "":
+		failures = append(failures, fmt.Sprintf("torch %s is a CPU-only build", info.TorchVersion))
+		check.Remediation = fmt.Sprintf("Reinstall %s with a CUDA torch build: hermes install --install %s --upgrade --extra-index-url %s",
+			name, name, torchIndexURL(inv.CUDAVersion))
+	case inv.CUDAVersion == "":
+		warnings = append(warnings, fmt.Sprintf("driver %s does not report its CUDA version", inv.DriverVersion))
+	default:
+		driver, torch := parseMajorMinor(inv.CUDAVersion), parseMajorMinor(torchCUDA)
+		switch {
+		case driver[0] < torch[0]:
+			failures = append(failures, fmt.Sprintf(
+				"torch %s was built for CUDA %s, but driver %s supports CUDA %s at most; CUDA %d.x needs a driver that supports it",
+				info.TorchVersion, torchCUDA, inv.DriverVersion, inv.CUDAVersion, torch[0]))
+		case driver[0] == torch[0] && driver[1] < torch[1]:
+			warnings = append(warnings, fmt.Sprintf(
+				"torch %s was built for CUDA %s, newer than the CUDA %s supported by driver %s; prebuilt kernels run under minor-version compatibility, but kernels compiled at runtime (Triton, FlashInfer) may fail with an unsupported PTX toolchain error",
+				info.TorchVersion, torchCUDA, inv.CUDAVersion, inv.DriverVersion))
+		}
+		if len(failures)+len(warnings) > 0 {
+			check.Remediation = fmt.Sprintf("Upgrade the NVIDIA driver to one supporting CUDA %s, or reinstall with a matching torch: hermes install --install %s --upgrade --extra-index-url %s",
+				torchCUDA, name, torchIndexURL(inv.CUDAVersion))
+		}
+	}
+
+	minCC := engine.Get(name).Capabilities().MinComputeCapability
+	for _, g := range inv.GPUs {
+		if g.ComputeCapability == "" {
+			continue
+		}
+		cc := parseMajorMinor(g.ComputeCapability)
+		if minCC != "" && compareMajorMinor(cc, parseMajorMinor(minCC)) < 0 {
+			failures = append(failures, fmt.Sprintf("GPU%d (%s) has compute capability %s; %s needs %s or newer",
+				g.Index, g.Name, g.ComputeCapability, name, minCC))
+			continue
+		}
+		if len(info.TorchArchs) > 0 && !archSupported(cc, info.TorchArchs) {
+			failures = append(failures, fmt.Sprintf("GPU%d (%s) is sm_%d%d, but torch %s was compiled only for %s",
+				g.Index, g.Name, cc[0], cc[1], info.TorchVersion, strings.Join(info.TorchArchs, ", ")))
+			if check.Remediation == "" {
+				check.Remediation = fmt.Sprintf("Reinstall with a torch build that includes sm_%d%d: hermes install --install %s --upgrade --extra-index-url %s",
+					cc[0], cc[1], name, torchIndexURL(inv.CUDAVersion))
+			}
+		}
+	}
+
+	switch {
+	case len(failures) > 0:
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("%s is not compatible with this host", name)
+	case len(warnings) > 0:
+		check.Status = StatusWarning
+		check.Message = fmt.Sprintf("%s may not be fully compatible with this host", name)
+	default:
+		check.Status = StatusOK
+		check.Message = fmt.Sprintf("%s: torch CUDA %s, driver supports CUDA %s", name, info.TorchCUDA, inv.CUDAVersion)
+		return check
+	}
+	check.Details = strings.Join(append(failures, warnings...), "\n")
+	return check
+}
+
+// archSupported reports whether code for one of archs runs on a GPU of
+// compute capability cc: an sm_XY binary runs on the same major version with
+// an equal or higher minor, and compute_XY PTX is compiled for any newer GPU.
+func archSupported(cc [2]int, archs []string) bool {
+	for _, arch := range archs {
+		kind, digits, ok := strings.Cut(arch, "_")
+		// Suffixed targets such as sm_90a are matched on their digits.
+		digits = strings.TrimRight(digits, "abcdefghijklmnopqrstuvwxyz")
+		if !ok || len(digits) < 2 {
+			continue
+		}
+		major, err1 := strconv.Atoi(digits[:len(digits)-1])
+		minor, err2 := strconv.Atoi(digits[len(digits)-1:])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		built := [2]int{major, minor}
+		switch kind {
+		case "sm":
+			if built[0] == cc[0] && built[1] <= cc[1] {
+				return true
+			}
+		case "compute":
+			if compareMajorMinor(built, cc) <= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// torchIndexURL picks the newest torch CUDA build the driver can run.
+func torchIndexURL(driverCUDA string) string {
+	driver := parseMajorMinor(driverCUDA)
+	pick := torchCUDAIndexes[0]
+	for _, v := range torchCUDAIndexes {
+		if compareMajorMinor(parseMajorMinor(v), driver) <= 0 {
+			pick = v
+		}
+	}
+	return "https://download.pytorch.org/whl/cu" + strings.ReplaceAll(pick, ".", "")
+}
+
+// parseMajorMinor reads "12.4" or "12.4.1" as {12, 4}; missing or malformed
+// parts are zero.
+func parseMajorMinor(v string) [2]int {
+	var mm [2]int
+	parts := strings.SplitN(strings.TrimSpace(v), ".", 3)
+	for i := 0; i < len(parts) && i < 2; i++ {
+		mm[i], _ = strconv.Atoi(parts[i])
+	}
+	return mm
+}
+
+func compareMajorMinor(a, b [2]int) int {
+	if a[0] != b[0] {
+		return a[0] - b[0]
+	}
+	return a[1] - b[1]
+}
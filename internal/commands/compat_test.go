@@ -0,0 +1,83 @@
+package commands
+
+import "testing"
+
+func TestArchSupported(t *testing.T) {
+	tests := []struct {
+		cc    [2]int
+		archs []string
+		want  bool
+	}{
+		// sm_XY binaries run on the same major at an equal or higher minor.
+		{[2]int{8, 0}, []string{"sm_80"}, true},
+		{[2]int{8, 6}, []string{"sm_80"}, true},
+		{[2]int{8, 0}, []string{"sm_86"}, false},
+		{[2]int{9, 0}, []string{"sm_80", "sm_86"}, false},
+		{[2]int{8, 9}, []string{"sm_70", "sm_75", "sm_80", "sm_86", "sm_90"}, true},
+		// Suffixed and three-digit targets.
+		{[2]int{9, 0}, []string{"sm_90a"}, true},
+		{[2]int{10, 0}, []string{"sm_100"}, true},
+		{[2]int{12, 0}, []string{"sm_120a"}, true},
+		{[2]int{10, 0}, []string{"sm_90a"}, false},
+		// compute_XY PTX is JIT-compiled for any newer GPU.
+		{[2]int{9, 0}, []string{"sm_80", "compute_80"}, true},
+		{[2]int{12, 0}, []string{"compute_90"}, true},
+		{[2]int{9, 0}, []string{"compute_90a"}, true},
+		{[2]int{7, 5}, []string{"compute_80"}, false},
+		// Entries that are not architectures are ignored.
+		{[2]int{8, 0}, nil, false},
+		{[2]int{8, 0}, []string{"sm", "sm_8", "sm_xy", "lto_80"}, false},
+	}
+	for _, tt := range tests {
+		if got := archSupported(tt.cc, tt.archs); got != tt.want {
+			t.Errorf("archSupported(%d.%d, %q) = %v, want %v", tt.cc[0], tt.cc[1], tt.archs, got, tt.want)
+		}
+	}
+}
+
+func TestTorchIndexURL(t *testing.T) {
+	tests := []struct {
+		driverCUDA string
+		want       string
+	}{
+		{"", "cu118"},
+		{"11.4", "cu118"},
+		{"11.8", "cu118"},
+		{"12.0", "cu118"},
+		{"12.1", "cu121"},
+		{"12.3", "cu121"},
+		{"12.4", "cu124"},
+		{"12.5", "cu124"},
+		{"12.6", "cu126"},
+		{"12.7", "cu126"},
+		{"12.8", "cu128"},
+		{"12.9", "cu128"},
+		{"13.0", "cu128"},
+	}
+	for _, tt := range tests {
+		want := "https://download.pytorch.org/whl/" + tt.want
+		if got := torchIndexURL(tt.driverCUDA); got != want {
+			t.Errorf("torchIndexURL(%q) = %q, want %q", tt.driverCUDA, got, want)
+		}
+	}
+}
+
+func TestParseMajorMinor(t *testing.T) {
+	tests := []struct {
+		v    string
+		want [2]int
+	}{
+		{"12.4", [2]int{12, 4}},
+		{"12.4.1", [2]int{12, 4}},
+		{" 11.8\n", [2]int{11, 8}},
+		{"12", [2]int{12, 0}},
+		{"", [2]int{0, 0}},
+		{"N/A", [2]int{0, 0}},
+		{"12.x", [2]int{12, 0}},
+	}
+	for _, tt := range tests {
+		if got := parseMajorMinor(tt.v); got != tt.want {
+			t.Errorf("parseMajorMinor(%q) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeRuntime is a docker or podman stand-in that appends its argv, one
+// tab-separated line per invocation, to the file in FAKE_RUNTIME_LOG.
+const fakeRuntime = `#!/bin/sh
+{ for a in "$@"; do printf '%s\t' "$a"; done; printf '\n'; } >> "$FAKE_RUNTIME_LOG"
+case "$1" in
+inspect)
+	if [ "$3" = "{{.State.Running}}" ]; then echo true; else echo 0123abcd; fi ;;
+run)
+	echo 0123abcd ;;
+logs)
+	echo "INFO server ready" ;;
+esac
+`
+
+// setupFakeRuntime puts a fake runtime binary first on PATH and isolates
+// hermes' state and the HF cache in temporary directories. It returns the
+// path of the argv log.
+func setupFakeRuntime(t *testing.T, runtime string) string {
+	t.Helper()
+	isolateHome(t)
+	log := filepath.Join(t.TempDir(), "argv.log")
+	t.Setenv("FAKE_RUNTIME_LOG", log)
+	fakeCommand(t, runtime, fakeRuntime)
+	t.Setenv("HF_TOKEN", "secret")
+	return log
+}
+
+// invocations reads the argv log, one slice per runtime call.
+func invocations(t *testing.T, log string) [][]string {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls [][]string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		calls = append(calls, strings.Split(strings.TrimSuffix(line, "\t"), "\t"))
+	}
+	return calls
+}
+
+// findCalls returns the invocations whose subcommand is sub.
+func findCalls(calls [][]string, sub string) [][]string {
+	var found [][]string
+	for _, c := range calls {
+		if len(c) > 0 && c[0] == sub {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+func TestServeContainerRunArgs(t *testing.T) {
+	tests := []struct {
+		runtime string
+		gpuArgs []string
+	}{
+		{"docker", []string{"--gpus", `"device=0,1"`}},
+		{"podman", []string{"--device", "nvidia.com/gpu=0", "--device", "nvidia.com/gpu=1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.runtime, func(t *testing.T) {
+			log := setupFakeRuntime(t, tt.runtime)
+			ctx, out := newTestContext(t)
+
+			err := Serve(ctx, []string{
+				"--engine", "vllm", "--model", "org/model", "--runtime", tt.runtime,
+				"--daemon", "--gpus", "0,1", "--tp", "2", "--host", "127.0.0.1", "--port", "8001",
+				"--shm-size", "32g", "--env", "FOO=bar",
+			})
+			if err != nil {
+				t.Fatalf("serve: %v\n%s", err, out)
+			}
+
+			runs := findCalls(invocations(t, log), "run")
+			if len(runs) != 1 {
+				t.Fatalf("got %d run invocations, want 1", len(runs))
+			}
+			want := []string{"run", "--name", "hermes-vllm-8001", "--detach"}
+			want = append(want, tt.gpuArgs...)
+			want = append(want,
+				"--publish", "127.0.0.1:8001:8001",
+				"--shm-size", "32g",
+				"--volume", os.Getenv("HF_HOME")+":/root/.cache/huggingface",
+				"--env", "HF_TOKEN",
+				"--env", "CUDA_DEVICE_ORDER=PCI_BUS_ID",
+				"--env", "FOO=bar",
+				"--entrypoint", "vllm",
+				"vllm/vllm-openai:latest",
+				"serve", "org/model", "--host", "0.0.0.0", "--port", "8001",
+				"--tensor-parallel-size", "2", "--trust-remote-code",
+			)
+			if !slices.Equal(runs[0], want) {
+				t.Errorf("run invocation:\n got %q\nwant %q", runs[0], want)
+			}
+		})
+	}
+}
+
+func TestContainerInstanceLifecycle(t *testing.T) {
+	log := setupFakeRuntime(t, "docker")
+	ctx, out := newTestContext(t)
+
+	if err := Serve(ctx, []string{"--engine", "vllm", "--model", "org/model", "--runtime", "docker", "--daemon", "--port", "8001"}); err != nil {
+		t.Fatalf("serve: %v\n%s", err, out)
+	}
+	os.Truncate(log, 0)
+
+	out.Reset()
+	if err := Ps(ctx, []string{"--json"}); err != nil {
+		t.Fatalf("ps: %v", err)
+	}
+	var instances []Instance
+	if err := json.Unmarshal(out.Bytes(), &instances); err != nil {
+		t.Fatalf("ps output: %v\n%s", err, out)
+	}
+	if len(instances) != 1 || instances[0].Container != "hermes-vllm-8001" || instances[0].Runtime != "docker" {
+		t.Fatalf("ps listed %+v", instances)
+	}
+	want := [][]string{{"inspect", "--format", "{{.State.Running}}", "hermes-vllm-8001"}}
+	if calls := invocations(t, log); !slices.EqualFunc(calls, want, slices.Equal) {
+		t.Errorf("ps invocations:\n got %q\nwant %q", calls, want)
+	}
+
+	os.Truncate(log, 0)
+	out.Reset()
+	if err := Logs(ctx, []string{"vllm-8001", "--tail", "50"}); err != nil {
+		t.Fatalf("logs: %v", err)
+	}
+	if !strings.Contains(out.String(), "INFO server ready") {
+		t.Errorf("logs output %q does not contain the container's log", out)
+	}
+	want = [][]string{
+		{"inspect", "--format", "{{.State.Running}}", "hermes-vllm-8001"},
+		{"logs", "--tail", "50", "hermes-vllm-8001"},
+	}
+	if calls := invocations(t, log); !slices.EqualFunc(calls, want, slices.Equal) {
+		t.Errorf("logs invocations:\n got %q\nwant %q", calls, want)
+	}
+
+	os.Truncate(log, 0)
+	if err := Stop(ctx, []string{"vllm-8001", "--timeout", "10"}); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	want = [][]string{
+		{"inspect", "--format", "{{.State.Running}}", "hermes-vllm-8001"},
+		{"stop", "--time", "10", "hermes-vllm-8001"},
+		{"inspect", "--format", "{{.Id}}", "hermes-vllm-8001"},
+		{"rm", "--force", "hermes-vllm-8001"},
+	}
+	if calls := invocations(t, log); !slices.EqualFunc(calls, want, slices.Equal) {
+		t.Errorf("stop invocations:\n got %q\nwant %q", calls, want)
+	}
+	if remaining, _ := listInstances(); len(remaining) != 0 {
+		t.Errorf("instance still registered after stop: %+v", remaining)
+	}
+}
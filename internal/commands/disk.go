@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/engine"
+	"github.com/svngoku/hermes-cli/internal/hf"
+)
+
+const gib = 1 << 30
+
+// Free space below which the disk checks warn. A CUDA torch environment
+// takes about 10 GiB; model downloads are checked against their own size.
+const (
+	minCacheFree = 50 * gib
+	minEnvFree   = 20 * gib
+	minLogFree   = 1 * gib
+	// cacheHeadroom is what must remain after a download, for the engine's
+	// compiled kernels and other caches on the same volume.
+	cacheHeadroom = 10 * gib
+)
+
+// diskFree returns the space available to unprivileged users on the
+// filesystem holding path, which need not exist yet: its nearest existing
+// ancestor is measured instead.
+func diskFree(path string) (free int64, measured string, err error) {
+	measured = path
+	for {
+		if _, err := os.Stat(measured); err == nil {
+			break
+		}
+		parent := filepath.Dir(measured)
+		if parent == measured {
+			break
+		}
+		measured = parent
+	}
+	var st unix.Statfs_t
+	if err := unix.Statfs(measured, &st); err != nil {
+		return 0, measured, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), measured, nil
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= gib:
+		return fmt.Sprintf("%.1f GiB", float64(n)/gib)
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%d KiB", n>>10)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// checkCacheDisk reports free space and cached models in the HuggingFace hub
+// cache. With a model repo to check, its uncached files must fit.
+func checkCacheDisk(ctx *app.AppContext, run *checkRun) CheckResult {
+	var check CheckResult
+
+	dir := hf.HubCache()
+	free, measured, err := diskFree(dir)
+	if err != nil {
+		check.Status = StatusWarning
+		check.Message = fmt.Sprintf("Could not measure free space at %s", measured)
+		check.Details = err.Error()
+		return check
+	}
+
+	var details []string
+	if models, err := hf.CachedModels(dir); err == nil && len(models) > 0 {
+		var total int64
+		var largest []string
+		for i, m := range models {
+			total += m.Bytes
+			if i < 3 {
+				largest = append(largest, fmt.Sprintf("%s %s", m.Repo, formatBytes(m.Bytes)))
+			}
+		}
+		details = append(details, fmt.Sprintf("%d cached model(s), %s (largest: %s)", len(models), formatBytes(total), strings.Join(largest, ", ")))
+	}
+
+	check.Status = StatusOK
+	check.Message = fmt.Sprintf("HF cache: %s free at %s", formatBytes(free), dir)
+	if free < minCacheFree {
+		check.Status = StatusWarning
+		check.Message = fmt.Sprintf("HF cache: only %s free at %s", formatBytes(free), dir)
+	}
+	// A missing cache is often HF_HOME pointing at a volume that is not
+	// mounted, in which case models would fill the volume below it.
+	if measured != dir && check.Status == StatusOK {
+		check.Status = StatusWarning
+		check.Message = fmt.Sprintf("HF cache %s does not exist; %s free at %s", dir, formatBytes(free), measured)
+		check.Remediation = "Create it with mkdir -p " + dir + ", after checking that its volume is mounted"
+	}
+
+	if run.model != "" && hf.IsRepoID(run.model) {
+		d, err := hf.EstimateDownload(ctx.Ctx, ctx.HTTPClient(), dir, run.model)
+		switch {
+		case err != nil:
+			if check.Status == StatusOK {
+				check.Status = StatusWarning
+				check.Message += fmt.Sprintf("; download size of %s unknown", run.model)
+				check.Remediation = "Check the model name and access to " + hf.Endpoint() + "; gated models need HF_TOKEN"
+			}
+			details = append(details, fmt.Sprintf("could not estimate the download of %s: %v", run.model, err))
+		case d.Needed > free:
+			check.Status = StatusFail
+			check.Message = fmt.Sprintf("%s needs %s to download, but only %s is free at %s",
+				run.model, formatBytes(d.Needed), formatBytes(free), dir)
+		case free-d.Needed < cacheHeadroom:
+			check.Status = StatusWarning
+			check.Message = fmt.Sprintf("%s fits, leaving only %s free at %s",
+				run.model, formatBytes(free-d.Needed), dir)
+		}
+		if err == nil {
+			details = append(details, fmt.Sprintf("%s: %s in %d file(s), %s cached, %s to download",
+				run.model, formatBytes(d.Total), d.Files, formatBytes(d.Cached), formatBytes(d.Needed)))
+		}
+	} else if run.model != "" {
+		details = append(details, fmt.Sprintf("%s is a local path; no download needed", run.model))
+	}
+	check.Details = strings.Join(details, "\n")
+	return check
+}
+
+// checkEnvDisk reports free space where engine environments are created,
+// including any --env-root recorded at install time.
+func checkEnvDisk(ctx *app.AppContext, run *checkRun) CheckResult {
+	dirs := []string{engine.DefaultEnvRoot()}
+	if state, err := loadState(); err == nil {
+		for _, name := range engine.Names() {
+			if st, ok := state.Engines[string(name)]; ok && st.EnvPath != "" {
+				dirs = append(dirs, filepath.Dir(st.EnvPath))
+			}
+		}
+	}
+	return checkFreeSpace("Engine environments", dirs, minEnvFree)
+}
+
+// checkLogDisk reports free space for the log file and hermes' own state,
+// where instance records are kept.
+func checkLogDisk(ctx *app.AppContext, run *checkRun) CheckResult {
+	dirs := []string{filepath.Dir(getStateFilePath())}
+	if ctx.LogFile != "" {
+		dirs = append(dirs, filepath.Dir(absPath(ctx.LogFile)))
+	}
+	return checkFreeSpace("Logs", dirs, minLogFree)
+}
+
+// checkFreeSpace measures each of dirs once and warns when any has less
+// than want free.
+func checkFreeSpace(what string, dirs []string, want int64) CheckResult {
+	var check CheckResult
+	check.Status = StatusOK
+
+	seen := make(map[string]bool)
+	var lines []string
+	lowest, lowestDir := int64(-1), ""
+	for _, dir := range dirs {
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		free, measured, err := diskFree(dir)
+		if err != nil {
+			check.Status = StatusWarning
+			lines = append(lines, fmt.Sprintf("%s: %v", measured, err))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s free", dir, formatBytes(free)))
+		if lowest < 0 || free < lowest {
+			lowest, lowestDir = free, dir
+		}
+	}
+
+	switch {
+	case lowest >= 0 && lowest < want:
+		check.Status = StatusWarning
+		check.Message = fmt.Sprintf("%s: only %s free at %s (want %s)", what, formatBytes(lowest), lowestDir, formatBytes(want))
+	case lowest >= 0:
+		check.Message = fmt.Sprintf("%s: %s free at %s", what, formatBytes(lowest), lowestDir)
+	default:
+		check.Message = fmt.Sprintf("%s: could not measure free space", what)
+	}
+	if len(lines) > 1 || check.Status != StatusOK {
+		check.Details = strings.Join(lines, "\n")
+	}
+	return check
+}
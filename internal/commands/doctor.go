@@ -9,7 +9,10 @@ import (
 	"strings"
 
 	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/engine"
 	"github.com/svngoku/hermes-cli/internal/execx"
+	"github.com/svngoku/hermes-cli/internal/gpu"
 	"github.com/svngoku/hermes-cli/internal/ui"
 )
 
@@ -23,22 +26,48 @@ const (
 )
 
 type CheckResult struct {
-	Name    string      `json:"name"`
-	Status  CheckStatus `json:"status"`
-	Message string      `json:"message,omitempty"`
-	Details string      `json:"details,omitempty"`
+	Name        string      `json:"name"`
+	Category    string      `json:"category,omitempty"`
+	Severity    Severity    `json:"severity,omitempty"`
+	Status      CheckStatus `json:"status"`
+	Message     string      `json:"message,omitempty"`
+	Details     string      `json:"details,omitempty"`
+	Remediation string      `json:"remediation,omitempty"`
+	DurationMs  int64       `json:"duration_ms"`
 }
 
 type DoctorReport struct {
-	Checks   []CheckResult `json:"checks"`
-	Summary  string        `json:"summary"`
-	ExitCode int           `json:"exit_code"`
+	Checks   []CheckResult    `json:"checks"`
+	GPUs     []gpu.GPU        `json:"gpus,omitempty"`
+	Topology *gpu.Topology    `json:"topology,omitempty"`
+	TPGroup  *TPGroup         `json:"tp_group,omitempty"`
+	Engines  []engine.EnvInfo `json:"engines,omitempty"`
+	Summary  string           `json:"summary"`
+	ExitCode int              `json:"exit_code"`
+}
+
+// checkListing is a registry entry as printed by `doctor --list --json`.
+type checkListing struct {
+	ID          string   `json:"id"`
+	Category    string   `json:"category"`
+	Severity    Severity `json:"severity"`
+	Description string   `json:"description"`
+	DependsOn   []string `json:"depends_on,omitempty"`
+	Remediation string   `json:"remediation,omitempty"`
 }
 
 func Doctor(ctx *app.AppContext, args []string) error {
 	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
 	jsonOutput := fs.Bool("json", false, "Output in JSON format")
 	strict := fs.Bool("strict", false, "Fail if any check is missing")
+	list := fs.Bool("list", false, "List the available checks and exit")
+	model := fs.String("model", "", "Model repo to check the download size of against free cache space")
+	tp := fs.Int("tp", 1, "Tensor parallel size to size the checks and recommend GPUs for")
+	fix := fs.Bool("fix", false, "Offer to fix failed checks, then re-run them")
+	yes := fs.Bool("yes", false, "Apply --fix remediations without asking")
+	var only, skip stringList
+	fs.Var(&only, "only", "Run only these checks, by ID or category (comma-separated, repeatable)")
+	fs.Var(&skip, "skip", "Skip these checks, by ID or category (comma-separated, repeatable)")
 	fs.Usage = func() {
 		fmt.Fprintln(ctx.Stdout, "Usage: hermes doctor [flags]")
 		fmt.Fprintln(ctx.Stdout)
@@ -50,6 +79,35 @@ func Doctor(ctx *app.AppContext, args []string) error {
 		return err
 	}
 
+	if *tp < 1 {
+		return fmt.Errorf("invalid --tp %d (must be at least 1)", *tp)
+	}
+	if *fix && *jsonOutput {
+		return fmt.Errorf("--fix cannot be combined with --json")
+	}
+	if *yes && !*fix {
+		return fmt.Errorf("--yes is only used with --fix")
+	}
+
+	checks, err := selectChecks(doctorChecks(), splitSelectors(only), splitSelectors(skip))
+	if err != nil {
+		return err
+	}
+
+	if *list {
+		if *jsonOutput {
+			listing := make([]checkListing, 0, len(checks))
+			for _, c := range checks {
+				listing = append(listing, checkListing{c.ID, c.Category, c.Severity, c.Description, c.DependsOn, c.Remediation})
+			}
+			enc := json.NewEncoder(ctx.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(listing)
+		}
+		printCheckList(ctx, checks)
+		return nil
+	}
+
 	report := DoctorReport{
 		Checks: make([]CheckResult, 0),
 	}
@@ -60,11 +118,23 @@ func Doctor(ctx *app.AppContext, args []string) error {
 		fmt.Fprintln(ctx.Stdout, ui.HR())
 	}
 
-	report.Checks = append(report.Checks, checkNvidiaSMI(ctx, *jsonOutput))
-	report.Checks = append(report.Checks, checkCUDA(ctx, *jsonOutput))
-	report.Checks = append(report.Checks, checkGPUs(ctx, *jsonOutput))
-	report.Checks = append(report.Checks, checkUV(ctx, *jsonOutput))
-	report.Checks = append(report.Checks, checkPython(ctx, *jsonOutput))
+	run := newCheckRun()
+	run.model = *model
+	run.tp = *tp
+	run.only = splitSelectors(only)
+	if *jsonOutput {
+		report.Checks = runChecks(ctx, run, checks, nil)
+	} else {
+		report.Checks = displayChecks(ctx, run, checks)
+	}
+	if *fix {
+		report.Checks = fixChecks(ctx, run, checks, report.Checks, *yes)
+	}
+	if inv := run.gpuInventory(); inv != nil {
+		report.GPUs = inv.GPUs
+	}
+	report.Topology, report.TPGroup = run.topology, run.tpGroup
+	report.Engines = run.engineInfos()
 
 	hasOK := false
 	hasWarn := false
@@ -114,158 +184,204 @@ func Doctor(ctx *app.AppContext, args []string) error {
 		fmt.Fprintln(ctx.Stdout, ui.Fail(report.Summary))
 	}
 
+	// os.Exit skips deferred cleanup, which saves a recorded cassette.
+	ctx.Close()
 	os.Exit(report.ExitCode)
 	return nil
 }
 
-func checkNvidiaSMI(ctx *app.AppContext, jsonOut bool) CheckResult {
-	check := CheckResult{Name: "nvidia-smi"}
+// splitSelectors flattens repeated, comma-separated selector flags.
+func splitSelectors(values []string) []string {
+	var selectors []string
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				selectors = append(selectors, s)
+			}
+		}
+	}
+	return selectors
+}
+
+func checkNvidiaSMI(ctx *app.AppContext, run *checkRun) CheckResult {
+	var check CheckResult
 
-	if !execx.CommandExists("nvidia-smi") {
+	if !ctx.Runner.CommandExists("nvidia-smi") {
 		check.Status = StatusFail
-		check.Message = "nvidia-smi not found"
-		if !jsonOut {
-			fmt.Fprintln(ctx.Stdout, ui.Fail("nvidia-smi not found; GPU not visible"))
-		}
+		check.Message = "nvidia-smi not found; GPU not visible"
 		return check
 	}
 
-	result := execx.Run(ctx.Ctx, "nvidia-smi", "--query-gpu=name,memory.total,driver_version", "--format=csv,noheader")
-	if result.ExitCode != 0 {
+	inv, err := gpu.LoadInventory(ctx.Ctx)
+	if err != nil {
 		check.Status = StatusFail
 		check.Message = "nvidia-smi failed"
-		check.Details = result.Stderr
-		if !jsonOut {
-			fmt.Fprintln(ctx.Stdout, ui.Fail("nvidia-smi failed: "+result.Stderr))
+		if execx.KindOf(err) == execx.KindTimeout {
+			check.Message = "nvidia-smi hung (driver may be wedged)"
 		}
+		check.Details = err.Error()
 		return check
 	}
+	run.setInventory(inv)
 
 	check.Status = StatusOK
-	check.Message = "GPU detected"
-	check.Details = result.Stdout
-	if !jsonOut {
-		fmt.Fprintln(ctx.Stdout, ui.Ok("nvidia-smi: GPU detected"))
-		for _, line := range strings.Split(result.Stdout, "\n") {
-			if strings.TrimSpace(line) != "" {
-				fmt.Fprintln(ctx.Stdout, "    "+line)
-			}
-		}
+	check.Message = fmt.Sprintf("nvidia-smi: driver %s, CUDA %s", inv.DriverVersion, orDash(inv.CUDAVersion))
+	var details []string
+	for _, g := range inv.GPUs {
+		details = append(details, fmt.Sprintf("GPU%d %s, %d MiB (%d MiB used)", g.Index, g.Name, g.MemoryTotalMiB, g.MemoryUsedMiB))
 	}
+	check.Details = strings.Join(details, "\n")
 	return check
 }
 
-func checkCUDA(ctx *app.AppContext, jsonOut bool) CheckResult {
-	check := CheckResult{Name: "cuda"}
+var nvccReleaseRe = regexp.MustCompile(`release (\d+\.\d+)`)
+
+func checkCUDA(ctx *app.AppContext, run *checkRun) CheckResult {
+	var check CheckResult
 
-	if !execx.CommandExists("nvcc") {
+	if !ctx.Runner.CommandExists("nvcc") {
 		check.Status = StatusWarning
 		check.Message = "nvcc not found (runtime-only image is fine)"
-		if !jsonOut {
-			fmt.Fprintln(ctx.Stdout, ui.Warn("nvcc not found (runtime-only image is fine)"))
-		}
 		return check
 	}
 
-	result := execx.Run(ctx.Ctx, "nvcc", "--version")
-	if result.ExitCode != 0 {
+	result := probe(ctx, "nvcc", "--version")
+	if result.Err != nil {
 		check.Status = StatusWarning
 		check.Message = "nvcc failed"
-		check.Details = result.Stderr
-		if !jsonOut {
-			fmt.Fprintln(ctx.Stdout, ui.Warn("nvcc failed: "+result.Stderr))
-		}
+		check.Details = result.Err.Error()
 		return check
 	}
 
-	versionRe := regexp.MustCompile(`release (\d+\.\d+)`)
-	matches := versionRe.FindStringSubmatch(result.Stdout)
 	version := "unknown"
-	if len(matches) > 1 {
+	if matches := nvccReleaseRe.FindStringSubmatch(result.Stdout); len(matches) > 1 {
 		version = matches[1]
 	}
-
 	check.Status = StatusOK
-	check.Message = "CUDA " + version
-	check.Details = result.Stdout
-	if !jsonOut {
-		fmt.Fprintln(ctx.Stdout, ui.Ok("CUDA toolchain: "+version))
-	}
+	check.Message = "CUDA toolchain: " + version
 	return check
 }
 
-func checkGPUs(ctx *app.AppContext, jsonOut bool) CheckResult {
-	check := CheckResult{Name: "gpu_count"}
+func checkGPUs(ctx *app.AppContext, run *checkRun) CheckResult {
+	var check CheckResult
 
-	result := execx.Run(ctx.Ctx, "nvidia-smi", "--query-gpu=count", "--format=csv,noheader")
-	if result.ExitCode != 0 {
+	inv := run.gpuInventory()
+	if inv == nil {
 		check.Status = StatusSkipped
-		check.Message = "Could not query GPU count"
+		check.Message = "GPU inventory unavailable"
 		return check
 	}
 
-	lines := strings.Split(strings.TrimSpace(result.Stdout), "\n")
-	count := len(lines)
-	if count > 0 && lines[0] != "" {
-		check.Status = StatusOK
-		check.Message = fmt.Sprintf("%d GPU(s) available", count)
-		if !jsonOut {
-			fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("%d GPU(s) available", count)))
-		}
-	} else {
+	if len(inv.GPUs) == 0 {
 		check.Status = StatusFail
 		check.Message = "No GPUs detected"
-		if !jsonOut {
-			fmt.Fprintln(ctx.Stdout, ui.Fail("No GPUs detected"))
+		return check
+	}
+	if len(inv.GPUs) < run.tp {
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("%d GPU(s) visible; TP=%d needs %d", len(inv.GPUs), run.tp, run.tp)
+		check.Remediation = "Lower --tp, or expose more GPUs (CUDA_VISIBLE_DEVICES, the container's --gpus option)"
+		return check
+	}
+	check.Status = StatusOK
+	check.Message = fmt.Sprintf("%d GPU(s) available", len(inv.GPUs))
+
+	// Uncorrectable ECC errors since boot usually mean the GPU must be reset
+	// before it can be trusted with a model.
+	var faulty []string
+	for _, g := range inv.GPUs {
+		if g.ECC != nil && g.ECC.VolatileUncorrected > 0 {
+			faulty = append(faulty, fmt.Sprintf("GPU%d: %d uncorrectable ECC errors since boot", g.Index, g.ECC.VolatileUncorrected))
 		}
 	}
+	if len(faulty) > 0 {
+		check.Status = StatusWarning
+		check.Details = strings.Join(faulty, "\n")
+		check.Remediation = "Drain the GPU and reset it with nvidia-smi -r -i <index>, or reboot"
+	}
 	return check
 }
 
-func checkUV(ctx *app.AppContext, jsonOut bool) CheckResult {
-	check := CheckResult{Name: "uv"}
+func checkUV(ctx *app.AppContext, run *checkRun) CheckResult {
+	var check CheckResult
 
-	if !execx.CommandExists("uv") {
+	if !ctx.Runner.CommandExists("uv") {
 		check.Status = StatusWarning
 		check.Message = "uv not found (will install during hermes install)"
-		if !jsonOut {
-			fmt.Fprintln(ctx.Stdout, ui.Warn("uv not found (will install during hermes install)"))
-		}
 		return check
 	}
 
-	result := execx.Run(ctx.Ctx, "uv", "--version")
-	version := strings.TrimSpace(result.Stdout)
-	check.Status = StatusOK
-	check.Message = version
-	if !jsonOut {
-		fmt.Fprintln(ctx.Stdout, ui.Ok("uv: "+version))
+	result := probe(ctx, "uv", "--version")
+	if result.Err != nil {
+		check.Status = StatusWarning
+		check.Message = "uv failed"
+		check.Details = result.Err.Error()
+		return check
 	}
+	check.Status = StatusOK
+	check.Message = strings.TrimSpace(result.Stdout)
 	return check
 }
 
-func checkPython(ctx *app.AppContext, jsonOut bool) CheckResult {
-	check := CheckResult{Name: "python"}
+func checkPython(ctx *app.AppContext, run *checkRun) CheckResult {
+	var check CheckResult
 
 	pythonCmd := "python3"
-	if !execx.CommandExists("python3") {
-		if !execx.CommandExists("python") {
+	if !ctx.Runner.CommandExists("python3") {
+		if !ctx.Runner.CommandExists("python") {
 			check.Status = StatusWarning
 			check.Message = "python not found"
-			if !jsonOut {
-				fmt.Fprintln(ctx.Stdout, ui.Warn("python not found"))
-			}
 			return check
 		}
 		pythonCmd = "python"
 	}
 
-	result := execx.Run(ctx.Ctx, pythonCmd, "--version")
-	version := strings.TrimSpace(result.Stdout)
+	result := probe(ctx, pythonCmd, "--version")
+	if result.Err != nil {
+		check.Status = StatusWarning
+		check.Message = "python failed"
+		check.Details = result.Err.Error()
+		return check
+	}
 	check.Status = StatusOK
-	check.Message = version
-	if !jsonOut {
-		fmt.Fprintln(ctx.Stdout, ui.Ok(version))
+	check.Message = strings.TrimSpace(result.Stdout)
+	return check
+}
+
+// probe runs a check's command with a timeout, so that a wedged driver
+// fails the check instead of hanging doctor.
+func probe(ctx *app.AppContext, name string, args ...string) execx.Result {
+	return execx.RunWith(execx.ReadOnly(ctx.Ctx), execx.Options{Timeout: execx.ProbeTimeout}, name, args...)
+}
+
+// checkEngineEnv inspects an engine's managed environment. An engine that
+// is not installed is skipped; an installed engine whose torch cannot see
+// CUDA is a warning.
+func checkEngineEnv(ctx *app.AppContext, run *checkRun, name config.Engine) CheckResult {
+	state, _ := loadState()
+	eng := stateEngine(state, name)
+	info, err := eng.CheckInstalled(ctx.Ctx)
+	run.setEngine(info)
+
+	var check CheckResult
+	switch {
+	case err != nil:
+		check.Status = StatusWarning
+		check.Message = fmt.Sprintf("%s: environment at %s is broken", eng.Name(), info.EnvPath)
+		check.Details = info.Error
+		check.Remediation = fmt.Sprintf("Recreate it with: hermes uninstall %s && hermes install --install %s", eng.Name(), eng.Name())
+	case !info.Installed:
+		check.Status = StatusSkipped
+		check.Message = fmt.Sprintf("%s not installed", eng.Name())
+	case !info.CUDAAvailable:
+		check.Status = StatusWarning
+		check.Message = fmt.Sprintf("%s %s: torch cannot use CUDA", eng.Name(), info.Version)
+		check.Details = describeEnv(info)
+		check.Remediation = "Install a torch build for your driver's CUDA version, e.g. with --extra-index-url https://download.pytorch.org/whl/cu124"
+	default:
+		check.Status = StatusOK
+		check.Message = fmt.Sprintf("%s %s", eng.Name(), info.Version)
+		check.Details = describeEnv(info)
 	}
 	return check
 }
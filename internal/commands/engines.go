@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/engine"
+	"github.com/svngoku/hermes-cli/internal/ui"
+)
+
+type EngineInfo struct {
+	Name            string              `json:"name"`
+	Capabilities    engine.Capabilities `json:"capabilities"`
+	ReadinessProbes []engine.Probe      `json:"readiness_probes"`
+}
+
+// probeDisplayConfig stands in for a real serve config so that model-dependent
+// probes such as the generation check are listed too.
+var probeDisplayConfig = config.ServeConfig{Model: "<model>"}
+
+func Engines(ctx *app.AppContext, args []string) error {
+	fs := flag.NewFlagSet("engines", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Output in JSON format")
+	fs.Usage = func() {
+		fmt.Fprintln(ctx.Stdout, "Usage: hermes engines [flags]")
+		fmt.Fprintln(ctx.Stdout)
+		fmt.Fprintln(ctx.Stdout, "List supported engines and their capabilities")
+		fmt.Fprintln(ctx.Stdout)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	engines := engine.All()
+
+	if *jsonOutput {
+		infos := make([]EngineInfo, 0, len(engines))
+		for _, eng := range engines {
+			infos = append(infos, EngineInfo{
+				Name:            eng.Name(),
+				Capabilities:    eng.Capabilities(),
+				ReadinessProbes: eng.ReadinessProbes(probeDisplayConfig),
+			})
+		}
+		enc := json.NewEncoder(ctx.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	}
+
+	fmt.Fprintln(ctx.Stdout, ui.Banner())
+	fmt.Fprintln(ctx.Stdout, ui.Step("Engine capabilities"))
+	fmt.Fprintln(ctx.Stdout, ui.HR())
+
+	rows := [][]string{{""}}
+	for _, eng := range engines {
+		rows[0] = append(rows[0], eng.Name())
+	}
+	features := []struct {
+		name string
+		get  func(engine.Capabilities) string
+	}{
+		{"health", func(c engine.Capabilities) string { return c.HealthPath }},
+		{"readiness", func(c engine.Capabilities) string { return c.ReadinessPath }},
+		{"metrics", func(c engine.Capabilities) string { return orDash(c.MetricsPath) }},
+		{"lora", func(c engine.Capabilities) string { return yesNo(c.LoRA) }},
+		{"embeddings", func(c engine.Capabilities) string { return yesNo(c.Embeddings) }},
+		{"multi-node", func(c engine.Capabilities) string { return yesNo(c.MultiNode) }},
+		{"speculative", func(c engine.Capabilities) string { return yesNo(c.SpeculativeDecoding) }},
+		{"min compute", func(c engine.Capabilities) string { return orDash(c.MinComputeCapability) }},
+	}
+	for _, f := range features {
+		row := []string{f.name}
+		for _, eng := range engines {
+			row = append(row, f.get(eng.Capabilities()))
+		}
+		rows = append(rows, row)
+	}
+	printTable(ctx, rows)
+
+	fmt.Fprintln(ctx.Stdout)
+	fmt.Fprintln(ctx.Stdout, "Readiness probes:")
+	for _, eng := range engines {
+		var stages []string
+		for _, p := range eng.ReadinessProbes(probeDisplayConfig) {
+			stages = append(stages, fmt.Sprintf("%s %s", p.Method, p.Path))
+		}
+		fmt.Fprintf(ctx.Stdout, "  %-9s %s\n", eng.Name(), strings.Join(stages, " → "))
+	}
+
+	fmt.Fprintln(ctx.Stdout, ui.HR())
+	printEngineOptions(ctx)
+	return nil
+}
+
+// printEngineOptions lists the per-engine values accepted by --quantization
+// and --tool-call-parser; it is shared by the serve and run help output.
+func printEngineOptions(ctx *app.AppContext) {
+	fmt.Fprintln(ctx.Stdout)
+	fmt.Fprintln(ctx.Stdout, "Engine options:")
+	for _, eng := range engine.All() {
+		caps := eng.Capabilities()
+		fmt.Fprintf(ctx.Stdout, "  %s\n", eng.Name())
+		fmt.Fprintf(ctx.Stdout, "    quantization:      %s\n", orDash(strings.Join(caps.Quantization, ", ")))
+		fmt.Fprintf(ctx.Stdout, "    tool-call-parser:  %s\n", orDash(strings.Join(caps.ToolCallParsers, ", ")))
+	}
+}
+
+func printTable(ctx *app.AppContext, rows [][]string) {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+	for _, row := range rows {
+		var b strings.Builder
+		for i, cell := range row {
+			b.WriteString(fmt.Sprintf("  %-*s", widths[i], cell))
+		}
+		fmt.Fprintln(ctx.Stdout, strings.TrimRight(b.String(), " "))
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
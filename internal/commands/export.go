@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/engine"
+	"github.com/svngoku/hermes-cli/internal/export"
+	"github.com/svngoku/hermes-cli/internal/ui"
+)
+
+// defaultBaseImage is used when the installed torch's CUDA version is unknown.
+const defaultBaseImage = "nvidia/cuda:12.4.1-runtime-ubuntu22.04"
+
+func Export(ctx *app.AppContext, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "bash", "Artifact to render: bash|dockerfile|compose")
+	output := fs.String("o", "", "Write to this file instead of standard output")
+	engineName := fs.String("engine", "sglang", "Engine: sglang|vllm|lmdeploy")
+	model := fs.String("model", "", "Model path or HuggingFace repo")
+	tp := fs.Int("tp", 4, "Tensor parallel size")
+	host := fs.String("host", "0.0.0.0", "Bind host")
+	port := fs.Int("port", 30000, "Bind port")
+	extraArgs := fs.String("extra-args", "", "Additional engine arguments")
+	backend := fs.String("backend", "", "lmdeploy backend: turbomind|pytorch")
+	quantization := fs.String("quantization", "", "Quantization method (see 'hermes engines')")
+	toolCallParser := fs.String("tool-call-parser", "", "Tool-call parser (see 'hermes engines')")
+	readinessTimeout := fs.Int("readiness-timeout", 300, "Readiness timeout in seconds for the generated checks")
+	gpus := fs.String("gpus", "", "Comma-separated GPU indexes to use (default: all)")
+	shmSize := fs.String("shm-size", "16g", "Container shared memory size (dockerfile, compose)")
+	version := fs.String("version", "", "Engine version to pin (default: the installed version)")
+	baseImage := fs.String("base-image", "", "Base image (default: CUDA runtime matching the installed torch)")
+	var env stringList
+	fs.Var(&env, "env", "Server environment variable as KEY=VALUE (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(ctx.Stdout, "Usage: hermes export [flags]")
+		fmt.Fprintln(ctx.Stdout)
+		fmt.Fprintln(ctx.Stdout, "Render the install and serve configuration as a standalone script,")
+		fmt.Fprintln(ctx.Stdout, "Dockerfile or compose file that runs without hermes")
+		fmt.Fprintln(ctx.Stdout)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	f, err := export.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	if *model == "" {
+		return fmt.Errorf("--model is required")
+	}
+	name := config.Engine(*engineName)
+	if engine.Get(name) == nil {
+		return fmt.Errorf("invalid engine: %s (use sglang, vllm or lmdeploy)", *engineName)
+	}
+	if *backend != "" && *backend != "turbomind" && *backend != "pytorch" {
+		return fmt.Errorf("invalid backend: %s (use turbomind or pytorch)", *backend)
+	}
+	for _, kv := range env {
+		if !strings.Contains(kv, "=") {
+			return fmt.Errorf("invalid --env %q (use KEY=VALUE)", kv)
+		}
+	}
+
+	cfg := config.ServeConfig{
+		Engine:           name,
+		Model:            *model,
+		TP:               *tp,
+		Host:             *host,
+		Port:             *port,
+		ExtraArgs:        *extraArgs,
+		Backend:          *backend,
+		Quantization:     *quantization,
+		ToolCallParser:   *toolCallParser,
+		ReadinessTimeout: *readinessTimeout,
+		GPUs:             *gpus,
+		ShmSize:          *shmSize,
+		Env:              env,
+	}
+	if cfg.GPUs == "auto" {
+		return fmt.Errorf("--gpus auto is resolved on the serving host; pass GPU indexes to export")
+	}
+	if f != export.Bash {
+		cfg.Host = "0.0.0.0"
+	}
+	// Bound to no environment so the command names binaries, which each
+	// artifact puts on PATH by activating its own virtualenv.
+	eng := engine.WithEnv(name, "")
+	if err := validateServeConfig(eng, cfg); err != nil {
+		return err
+	}
+
+	spec := resolveExportSpec(ctx, cfg, *version)
+	cmdName, cmdArgs := eng.ServeCommand(cfg)
+	spec.Command = append([]string{cmdName}, cmdArgs...)
+	spec.Probes = eng.ReadinessProbes(cfg)
+	spec.Host = *host
+	if *baseImage != "" {
+		spec.BaseImage = *baseImage
+	}
+
+	var buf bytes.Buffer
+	if err := export.Render(&buf, f, spec); err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err := ctx.Stdout.Write(buf.Bytes())
+		return err
+	}
+	mode := os.FileMode(0644)
+	if f == export.Bash {
+		mode = 0755
+	}
+	if err := os.WriteFile(*output, buf.Bytes(), mode); err != nil {
+		return err
+	}
+	fmt.Fprintln(ctx.Stderr, ui.Ok(fmt.Sprintf("Wrote %s %s", f, *output)))
+	return nil
+}
+
+// resolveExportSpec pins what is installed: the engine version, extras and
+// Python version are read from the managed environment, falling back to
+// install state. Anything that cannot be carried over becomes a note.
+func resolveExportSpec(ctx *app.AppContext, cfg config.ServeConfig, version string) export.Spec {
+	name := cfg.Engine
+	spec := export.Spec{
+		Engine:           string(name),
+		Model:            cfg.Model,
+		Env:              cfg.Env,
+		Port:             cfg.Port,
+		GPUs:             cfg.GPUs,
+		ShmSize:          cfg.ShmSize,
+		BaseImage:        defaultBaseImage,
+		ReadinessTimeout: cfg.ReadinessTimeout,
+	}
+
+	state, _ := loadState()
+	st := state.Engines[string(name)]
+	opts := engine.InstallOptions{Version: version}
+	if st != nil {
+		opts.Extras = st.Extras
+		spec.ExtraIndexURLs = st.ExtraIndexURLs
+		if len(st.Constraints) > 0 {
+			spec.Notes = append(spec.Notes, fmt.Sprintf("constraints files not included: %s", strings.Join(st.Constraints, ", ")))
+		}
+	}
+
+	info, err := stateEngine(state, name).CheckInstalled(ctx.Ctx)
+	switch {
+	case err == nil && info.Installed:
+		if opts.Version == "" {
+			opts.Version = info.Version
+		}
+		spec.Python = majorMinor(info.PythonVersion)
+		if cuda := majorMinor(info.TorchCUDA); cuda != "" {
+			spec.BaseImage = fmt.Sprintf("nvidia/cuda:%s.0-runtime-ubuntu22.04", cuda)
+		}
+		if info.TorchVersion != "" {
+			spec.Notes = append(spec.Notes, fmt.Sprintf("exported from an environment with torch %s (CUDA %s)", info.TorchVersion, orDash(info.TorchCUDA)))
+		}
+	case st != nil && st.Version != "" && opts.Version == "":
+		opts.Version = st.Version
+	}
+	if opts.Version == "" {
+		spec.Notes = append(spec.Notes, fmt.Sprintf("%s is not installed here; its version is not pinned", name))
+		fmt.Fprintln(ctx.Stderr, ui.Warn(fmt.Sprintf("%s is not installed; exporting without a version pin", name)))
+	}
+	spec.Requirement = engine.RequirementFor(name, opts)
+
+	if filepath.IsAbs(cfg.Model) && fileExists(cfg.Model) {
+		spec.LocalModel = cfg.Model
+		spec.Notes = append(spec.Notes, fmt.Sprintf("the model is read from %s, which must exist on the target machine", cfg.Model))
+	}
+	return spec
+}
+
+// majorMinor trims a version such as "3.11.9" or "12.4.1" to "3.11".
+func majorMinor(v string) string {
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0] + "." + parts[1]
+}
@@ -0,0 +1,254 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/engine"
+	"github.com/svngoku/hermes-cli/internal/hf"
+	"github.com/svngoku/hermes-cli/internal/ui"
+	"github.com/svngoku/hermes-cli/internal/ui/tui"
+)
+
+// fixAction is a remediation doctor --fix offers for a check that did not
+// pass. Apply is nil for changes that need root; their Commands are printed
+// for the user to run instead.
+type fixAction struct {
+	Title       string
+	Description string
+	Commands    []string
+	Apply       func(ctx *app.AppContext) error
+}
+
+// fixChecks offers the fixes for results that did not pass, applies those
+// confirmed (all of them with yes), and re-runs the fixed checks and the
+// checks that depend on them. It returns results with the re-run checks
+// replaced.
+func fixChecks(ctx *app.AppContext, run *checkRun, checks []Check, results []CheckResult, yes bool) []CheckResult {
+	fmt.Fprintln(ctx.Stdout, ui.HR())
+	fmt.Fprintln(ctx.Stdout, ui.Step("Fixing..."))
+
+	interactive := ui.IsTerminal(ctx.Stdout)
+	fixed := make(map[string]bool)
+	offered := 0
+	for i, c := range checks {
+		if c.Fix == nil || results[i].Status == StatusOK {
+			continue
+		}
+		action := c.Fix(ctx, run, results[i])
+		if action == nil {
+			continue
+		}
+		offered++
+
+		if action.Apply == nil {
+			fmt.Fprintln(ctx.Stdout, ui.Warn(fmt.Sprintf("%s (needs root, run yourself):", action.Title)))
+			for _, cmd := range action.Commands {
+				fmt.Fprintln(ctx.Stdout, "    "+cmd)
+			}
+			continue
+		}
+
+		apply := yes
+		if !yes {
+			if !interactive {
+				fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("%s: skipped (pass --yes to apply without asking)", action.Title)))
+				continue
+			}
+			ok, err := tui.ConfirmAction(action.Title+"?", action.Description)
+			if err != nil {
+				fmt.Fprintln(ctx.Stdout, ui.Warn(fmt.Sprintf("%s: %v", action.Title, err)))
+				continue
+			}
+			apply = ok
+		}
+		if !apply {
+			fmt.Fprintln(ctx.Stdout, ui.Info(action.Title+": skipped"))
+			continue
+		}
+		if err := action.Apply(ctx); err != nil {
+			fmt.Fprintln(ctx.Stdout, ui.Fail(fmt.Sprintf("%s failed: %v", action.Title, err)))
+			continue
+		}
+		fixed[c.ID] = true
+	}
+	if offered == 0 {
+		fmt.Fprintln(ctx.Stdout, ui.Info("No fixes available; see the suggestions above"))
+		return results
+	}
+	if len(fixed) == 0 {
+		return results
+	}
+
+	// Checks are registered after their dependencies, so one pass finds the
+	// dependents of dependents too.
+	var rerun []Check
+	for _, c := range checks {
+		affected := fixed[c.ID]
+		for _, dep := range c.DependsOn {
+			affected = affected || fixed[dep]
+		}
+		if affected {
+			fixed[c.ID] = true
+			rerun = append(rerun, c)
+		}
+	}
+	fmt.Fprintln(ctx.Stdout, ui.HR())
+	fmt.Fprintln(ctx.Stdout, ui.Step("Re-running fixed checks..."))
+	updated := displayChecks(ctx, run, rerun)
+
+	byID := make(map[string]CheckResult, len(updated))
+	for _, r := range updated {
+		byID[r.Name] = r
+	}
+	merged := append([]CheckResult(nil), results...)
+	for i, c := range checks {
+		if r, ok := byID[c.ID]; ok {
+			merged[i] = r
+		}
+	}
+	return merged
+}
+
+// fixUV installs uv the way hermes install does.
+func fixUV(ctx *app.AppContext, run *checkRun, result CheckResult) *fixAction {
+	if ctx.Runner.CommandExists("uv") {
+		return nil
+	}
+	return &fixAction{
+		Title:       "Install uv",
+		Description: "Downloads the uv installer from astral.sh into ~/.local/bin",
+		Apply: func(ctx *app.AppContext) error {
+			state, err := loadState()
+			if err != nil {
+				return err
+			}
+			if err := ensureUV(ctx, state, "", false); err != nil {
+				return err
+			}
+			return saveState(state)
+		},
+	}
+}
+
+// fixEngine installs an engine that is missing, creating its environment
+// first if needed. It is offered for engines hermes has installed before,
+// whose environment has since gone, and for engines named with --only;
+// doctor does not install every engine it knows of.
+func fixEngine(name config.Engine) func(*app.AppContext, *checkRun, CheckResult) *fixAction {
+	return func(ctx *app.AppContext, run *checkRun, result CheckResult) *fixAction {
+		info, ok := run.engine(string(name))
+		if !ok || info.Installed || info.Error != "" {
+			return nil
+		}
+		state, err := loadState()
+		if err != nil {
+			return nil
+		}
+		st, recorded := state.Engines[string(name)]
+		if !(recorded && st.EnvPath != "") && !run.requested("engine:"+string(name)) {
+			return nil
+		}
+		eng := stateEngine(state, name)
+		title := "Install " + string(name)
+		if recorded && st.Pin != "" {
+			title += " " + st.Pin
+		}
+		return &fixAction{
+			Title:       title,
+			Description: fmt.Sprintf("Creates the environment at %s if needed and installs %s into it", eng.Env().Path, name),
+			Apply: func(ctx *app.AppContext) error {
+				state, err := loadState()
+				if err != nil {
+					return err
+				}
+				st := state.Engine(string(name))
+				// The recorded state says installed; the check found otherwise.
+				st.Installed = false
+				opts := engine.InstallOptions{
+					Version:        st.Pin,
+					Extras:         st.Extras,
+					ExtraIndexURLs: st.ExtraIndexURLs,
+					Constraints:    st.Constraints,
+				}
+				err = installEngine(ctx, state, stateEngine(state, name), opts)
+				if saveErr := saveState(state); saveErr != nil {
+					ctx.Logger.Warn("failed to save state", "error", saveErr)
+				}
+				return err
+			},
+		}
+	}
+}
+
+// fixCacheDir creates a missing HuggingFace cache, or prints how to when its
+// parent is not writable.
+func fixCacheDir(ctx *app.AppContext, run *checkRun, result CheckResult) *fixAction {
+	dir := hf.HubCache()
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	_, parent, err := diskFree(dir)
+	if err != nil {
+		return nil
+	}
+	action := &fixAction{Title: "Create " + dir}
+	if unix.Access(parent, unix.W_OK) != nil {
+		action.Commands = []string{
+			"sudo mkdir -p " + dir,
+			fmt.Sprintf("sudo chown %d:%d %s", os.Getuid(), os.Getgid(), dir),
+		}
+		return action
+	}
+	action.Description = "Creates the HuggingFace hub cache directory"
+	action.Apply = func(ctx *app.AppContext) error {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		fmt.Fprintln(ctx.Stdout, ui.Ok("Created "+dir))
+		return nil
+	}
+	return action
+}
+
+// fixShm prints the remount that enlarges /dev/shm on a host; a container's
+// size is set when it starts.
+func fixShm(ctx *app.AppContext, run *checkRun, result CheckResult) *fixAction {
+	if result.Status == StatusSkipped || inContainer() {
+		return nil
+	}
+	return &fixAction{
+		Title:    "Enlarge /dev/shm",
+		Commands: []string{shmRemount(run.tp)},
+	}
+}
+
+// fixMemlock prints the limits.conf entries that raise the memlock limit.
+func fixMemlock(ctx *app.AppContext, run *checkRun, result CheckResult) *fixAction {
+	if inContainer() {
+		return nil
+	}
+	return limitsConfFix("memlock", "unlimited")
+}
+
+// fixNoFile prints the limits.conf entries that raise the open-file limit.
+func fixNoFile(ctx *app.AppContext, run *checkRun, result CheckResult) *fixAction {
+	if inContainer() {
+		return nil
+	}
+	return limitsConfFix("nofile", fmt.Sprint(noFileWant(run.tp)))
+}
+
+// limitsConfFix raises a limit for every user from their next login.
+func limitsConfFix(item, value string) *fixAction {
+	return &fixAction{
+		Title: fmt.Sprintf("Raise the %s limit from the next login", item),
+		Commands: []string{
+			fmt.Sprintf("printf '* soft %s %s\\n* hard %s %s\\n' | sudo tee -a /etc/security/limits.d/90-hermes.conf", item, value, item, value),
+		},
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/svngoku/hermes-cli/internal/app"
@@ -16,13 +17,57 @@ import (
 )
 
 type InstallState struct {
-	SGLangInstalled bool      `json:"sglang_installed"`
-	SGLangVersion   string    `json:"sglang_version,omitempty"`
-	VLLMInstalled   bool      `json:"vllm_installed"`
-	VLLMVersion     string    `json:"vllm_version,omitempty"`
-	UVInstalled     bool      `json:"uv_installed"`
-	VenvPath        string    `json:"venv_path,omitempty"`
-	LastUpdated     time.Time `json:"last_updated"`
+	Engines     map[string]*EngineState `json:"engines"`
+	UVInstalled bool                    `json:"uv_installed"`
+	LastUpdated time.Time               `json:"last_updated"`
+}
+
+// EngineState records what hermes installed for one engine. Pin is the
+// requested version specifier; Version is what uv actually resolved.
+// EnvPath is the engine's managed virtualenv.
+type EngineState struct {
+	Installed      bool     `json:"installed"`
+	EnvPath        string   `json:"env_path,omitempty"`
+	Version        string   `json:"version,omitempty"`
+	Pin            string   `json:"pin,omitempty"`
+	TorchVersion   string   `json:"torch_version,omitempty"`
+	TorchCUDA      string   `json:"torch_cuda,omitempty"`
+	Extras         []string `json:"extras,omitempty"`
+	ExtraIndexURLs []string `json:"extra_index_urls,omitempty"`
+	Constraints    []string `json:"constraints,omitempty"`
+}
+
+// legacyInstallState is the flat layout written before per-engine state;
+// it is still read so existing state files keep their versions.
+type legacyInstallState struct {
+	SGLangInstalled   bool   `json:"sglang_installed"`
+	SGLangVersion     string `json:"sglang_version"`
+	VLLMInstalled     bool   `json:"vllm_installed"`
+	VLLMVersion       string `json:"vllm_version"`
+	LMDeployInstalled bool   `json:"lmdeploy_installed"`
+	LMDeployVersion   string `json:"lmdeploy_version"`
+}
+
+// Engine returns the state for name, creating an empty entry if needed.
+func (s *InstallState) Engine(name string) *EngineState {
+	if s.Engines == nil {
+		s.Engines = make(map[string]*EngineState)
+	}
+	st, ok := s.Engines[name]
+	if !ok {
+		st = &EngineState{}
+		s.Engines[name] = st
+	}
+	return st
+}
+
+// stateEngine returns the engine bound to the environment recorded in state,
+// falling back to the default managed environment.
+func stateEngine(state *InstallState, name config.Engine) engine.Engine {
+	if st, ok := state.Engines[string(name)]; ok && st.EnvPath != "" {
+		return engine.WithEnv(name, st.EnvPath)
+	}
+	return engine.Get(name)
 }
 
 func getStateFilePath() string {
@@ -43,6 +88,17 @@ func loadState() (*InstallState, error) {
 	if err := json.Unmarshal(data, &state); err != nil {
 		return &InstallState{}, nil
 	}
+	if state.Engines == nil {
+		var legacy legacyInstallState
+		if err := json.Unmarshal(data, &legacy); err == nil {
+			state.Engine("sglang").Installed = legacy.SGLangInstalled
+			state.Engine("sglang").Version = legacy.SGLangVersion
+			state.Engine("vllm").Installed = legacy.VLLMInstalled
+			state.Engine("vllm").Version = legacy.VLLMVersion
+			state.Engine("lmdeploy").Installed = legacy.LMDeployInstalled
+			state.Engine("lmdeploy").Version = legacy.LMDeployVersion
+		}
+	}
 	return &state, nil
 }
 
@@ -60,70 +116,114 @@ func saveState(state *InstallState) error {
 	return os.WriteFile(path, data, 0644)
 }
 
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
 func Install(ctx *app.AppContext, args []string) error {
 	fs := flag.NewFlagSet("install", flag.ExitOnError)
-	installMode := fs.String("install", "both", "Install mode: sglang|vllm|both|none")
+	installMode := fs.String("install", "both", "Install mode: sglang|vllm|lmdeploy|both|none")
 	check := fs.Bool("check", false, "Check installation status without changes")
-	venvDir := fs.String("venv", ".venv", "Virtual environment directory")
+	jsonOutput := fs.Bool("json", false, "Output installation status in JSON format (with --check)")
+	envRoot := fs.String("env-root", engine.DefaultEnvRoot(), "Directory holding one managed virtualenv per engine")
+	upgrade := fs.Bool("upgrade", false, "Allow changing the version of an already-pinned engine")
+	versions := map[config.Engine]*string{
+		config.EngineSGLang:   fs.String("sglang-version", "", "sglang version or specifier (e.g. 0.4.6 or '>=0.4,<0.5')"),
+		config.EngineVLLM:     fs.String("vllm-version", "", "vllm version or specifier"),
+		config.EngineLMDeploy: fs.String("lmdeploy-version", "", "lmdeploy version or specifier"),
+	}
+	var indexURLs, constraints, extras stringList
+	fs.Var(&indexURLs, "extra-index-url", "Extra package index URL, e.g. for CUDA-specific torch wheels (repeatable)")
+	fs.Var(&constraints, "constraint", "pip constraints file (repeatable)")
+	fs.Var(&extras, "extras", "Engine extras as engine=extra[,extra] (repeatable, e.g. sglang=all)")
+	wheelhouse := fs.String("wheelhouse", "", "Install offline from a local wheelhouse or bundle directory")
+	uvBinary := fs.String("uv-binary", "", "Install uv from this local binary instead of downloading it")
 	fs.Usage = func() {
 		fmt.Fprintln(ctx.Stdout, "Usage: hermes install [flags]")
+		fmt.Fprintln(ctx.Stdout, "       hermes install bundle [flags]")
 		fmt.Fprintln(ctx.Stdout)
-		fmt.Fprintln(ctx.Stdout, "Install inference engines (sglang, vllm)")
+		fmt.Fprintln(ctx.Stdout, "Install inference engines (sglang, vllm, lmdeploy)")
 		fmt.Fprintln(ctx.Stdout)
 		fs.PrintDefaults()
 	}
+	if len(args) > 0 && args[0] == "bundle" {
+		return InstallBundle(ctx, args[1:])
+	}
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
 	mode := config.InstallMode(*installMode)
-	if mode != config.InstallSGLang && mode != config.InstallVLLM &&
+	if mode != config.InstallSGLang && mode != config.InstallVLLM && mode != config.InstallLMDeploy &&
 		mode != config.InstallBoth && mode != config.InstallNone {
 		return fmt.Errorf("invalid install mode: %s", *installMode)
 	}
 
-	fmt.Fprintln(ctx.Stdout, ui.Banner())
-	fmt.Fprintln(ctx.Stdout, ui.Step("Installation check..."))
-	fmt.Fprintln(ctx.Stdout, ui.HR())
+	engineExtras, err := parseExtras(extras)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput && !*check {
+		return fmt.Errorf("--json is only supported with --check")
+	}
 
 	state, _ := loadState()
 
-	if err := ensureUV(ctx, state); err != nil {
-		return err
+	if *check {
+		return installCheck(ctx, state, *jsonOutput)
 	}
 
-	if !*check && mode != config.InstallNone {
-		if err := setupVenv(ctx, *venvDir, state); err != nil {
+	fmt.Fprintln(ctx.Stdout, ui.Banner())
+	fmt.Fprintln(ctx.Stdout, ui.Step("Installation check..."))
+	fmt.Fprintln(ctx.Stdout, ui.HR())
+
+	wheels := ""
+	if *wheelhouse != "" {
+		bundle, err := filepath.Abs(*wheelhouse)
+		if err != nil {
+			return err
+		}
+		manifest, err := verifyBundle(bundle)
+		if err != nil {
 			return err
 		}
+		if manifest != nil {
+			fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("wheelhouse verified: %d files (built %s for %s)",
+				len(manifest.Files), manifest.CreatedAt.Format("2006-01-02"), manifest.Platform)))
+		} else {
+			fmt.Fprintln(ctx.Stdout, ui.Warn("wheelhouse has no manifest; checksums not verified"))
+		}
+		if *uvBinary == "" {
+			*uvBinary = bundledUV(bundle)
+		}
+		wheels = wheelDir(bundle)
 	}
 
-	sglangEngine := engine.Get(config.EngineSGLang)
-	vllmEngine := engine.Get(config.EngineVLLM)
-
-	installed, version, _ := sglangEngine.CheckInstalled(ctx.Ctx)
-	state.SGLangInstalled = installed
-	state.SGLangVersion = version
-	if installed {
-		fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("sglang: %s", version)))
-	} else {
-		fmt.Fprintln(ctx.Stdout, ui.Warn("sglang: not installed"))
+	if err := ensureUV(ctx, state, *uvBinary, wheels != ""); err != nil {
+		return err
 	}
 
-	installed, version, _ = vllmEngine.CheckInstalled(ctx.Ctx)
-	state.VLLMInstalled = installed
-	state.VLLMVersion = version
-	if installed {
-		fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("vllm: %s", version)))
-	} else {
-		fmt.Fprintln(ctx.Stdout, ui.Warn("vllm: not installed"))
+	envRootSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "env-root" {
+			envRootSet = true
+		}
+	})
+	root, err := filepath.Abs(*envRoot)
+	if err != nil {
+		return err
 	}
 
-	if *check {
-		fmt.Fprintln(ctx.Stdout, ui.HR())
-		fmt.Fprintln(ctx.Stdout, ui.Info("Check mode - no changes made"))
-		return nil
-	}
+	inspectEngines(ctx, state, true)
 
 	if mode == config.InstallNone {
 		fmt.Fprintln(ctx.Stdout, ui.HR())
@@ -134,63 +234,236 @@ func Install(ctx *app.AppContext, args []string) error {
 	fmt.Fprintln(ctx.Stdout, ui.HR())
 	fmt.Fprintln(ctx.Stdout, ui.Step("Installing engines..."))
 
-	if mode == config.InstallSGLang || mode == config.InstallBoth {
-		if !state.SGLangInstalled {
-			fmt.Fprintln(ctx.Stdout, ui.Info("Installing sglang..."))
-			if err := sglangEngine.Install(ctx.Ctx); err != nil {
-				fmt.Fprintln(ctx.Stdout, ui.Fail("sglang installation failed: "+err.Error()))
-				return err
+	for _, name := range modeEngines(mode) {
+		opts := engine.InstallOptions{
+			Version:        *versions[name],
+			Extras:         engineExtras[string(name)],
+			ExtraIndexURLs: indexURLs,
+			Constraints:    constraints,
+			Upgrade:        *upgrade,
+			Wheelhouse:     wheels,
+		}
+		st := state.Engine(string(name))
+		envPath := st.EnvPath
+		if envPath == "" || envRootSet {
+			envPath = filepath.Join(root, string(name))
+		}
+		err := installEngine(ctx, state, engine.WithEnv(name, envPath), opts)
+		if !ctx.DryRun {
+			if saveErr := saveState(state); saveErr != nil {
+				ctx.Logger.Warn("failed to save state", "error", saveErr)
 			}
-			installed, version, _ := sglangEngine.CheckInstalled(ctx.Ctx)
-			state.SGLangInstalled = installed
-			state.SGLangVersion = version
-			fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("sglang installed: %s", version)))
-		} else {
-			fmt.Fprintln(ctx.Stdout, ui.Ok("sglang already installed"))
+		}
+		if err != nil {
+			return err
 		}
 	}
 
-	if mode == config.InstallVLLM || mode == config.InstallBoth {
-		if !state.VLLMInstalled {
-			fmt.Fprintln(ctx.Stdout, ui.Info("Installing vllm..."))
-			if err := vllmEngine.Install(ctx.Ctx); err != nil {
-				fmt.Fprintln(ctx.Stdout, ui.Fail("vllm installation failed: "+err.Error()))
-				return err
-			}
-			installed, version, _ := vllmEngine.CheckInstalled(ctx.Ctx)
-			state.VLLMInstalled = installed
-			state.VLLMVersion = version
-			fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("vllm installed: %s", version)))
+	fmt.Fprintln(ctx.Stdout, ui.HR())
+	fmt.Fprintln(ctx.Stdout, ui.Ok("Installation complete"))
+
+	return nil
+}
+
+type InstallStatus struct {
+	UVInstalled bool             `json:"uv_installed"`
+	Engines     []engine.EnvInfo `json:"engines"`
+}
+
+// installCheck reports what is installed without touching the system.
+func installCheck(ctx *app.AppContext, state *InstallState, jsonOut bool) error {
+	status := InstallStatus{UVInstalled: ctx.Runner.CommandExists("uv")}
+
+	if !jsonOut {
+		fmt.Fprintln(ctx.Stdout, ui.Banner())
+		fmt.Fprintln(ctx.Stdout, ui.Step("Installation check..."))
+		fmt.Fprintln(ctx.Stdout, ui.HR())
+		if status.UVInstalled {
+			fmt.Fprintln(ctx.Stdout, ui.Ok("uv: found"))
 		} else {
-			fmt.Fprintln(ctx.Stdout, ui.Ok("vllm already installed"))
+			fmt.Fprintln(ctx.Stdout, ui.Warn("uv: not installed"))
 		}
 	}
 
-	if err := saveState(state); err != nil {
-		ctx.Logger.Warn("failed to save state", "error", err)
+	status.Engines = inspectEngines(ctx, state, !jsonOut)
+
+	if jsonOut {
+		enc := json.NewEncoder(ctx.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
 	}
 
 	fmt.Fprintln(ctx.Stdout, ui.HR())
-	fmt.Fprintln(ctx.Stdout, ui.Ok("Installation complete"))
+	fmt.Fprintln(ctx.Stdout, ui.Info("Check mode - no changes made"))
+	return nil
+}
 
+// inspectEngines queries every engine's environment, refreshes the recorded
+// state from what the interpreter reports and, if verbose, prints a summary.
+func inspectEngines(ctx *app.AppContext, state *InstallState, verbose bool) []engine.EnvInfo {
+	infos := make([]engine.EnvInfo, 0, len(engine.Names()))
+	for _, name := range engine.Names() {
+		eng := stateEngine(state, name)
+		info := refreshEngineState(ctx, state, eng)
+		st := state.Engine(eng.Name())
+		infos = append(infos, info)
+
+		if !verbose {
+			continue
+		}
+		if !info.Installed {
+			fmt.Fprintln(ctx.Stdout, ui.Warn(fmt.Sprintf("%s: not installed", eng.Name())))
+			continue
+		}
+		line := fmt.Sprintf("%s: %s", eng.Name(), info.Version)
+		if st.Pin != "" {
+			line += fmt.Sprintf(" (pinned %s)", st.Pin)
+		}
+		fmt.Fprintln(ctx.Stdout, ui.Ok(line))
+		fmt.Fprintln(ctx.Stdout, "    "+describeEnv(info))
+	}
+	return infos
+}
+
+// describeEnv summarises the interpreter and GPU stack of an environment.
+func describeEnv(info engine.EnvInfo) string {
+	parts := []string{fmt.Sprintf("python %s (%s)", info.PythonVersion, info.Interpreter)}
+	if info.TorchVersion != "" {
+		torch := "torch " + info.TorchVersion
+		if info.TorchCUDA != "" {
+			torch += " cuda " + info.TorchCUDA
+		}
+		parts = append(parts, torch)
+	}
+	if info.CUDAAvailable {
+		parts = append(parts, fmt.Sprintf("%d GPU(s) visible", info.DeviceCount))
+	} else {
+		parts = append(parts, "CUDA unavailable")
+	}
+	if info.FlashInfer != "" {
+		parts = append(parts, "flashinfer "+info.FlashInfer)
+	}
+	if info.Triton != "" {
+		parts = append(parts, "triton "+info.Triton)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// installEngine installs eng according to opts unless the recorded state
+// already satisfies the request. A pinned engine is never moved to another
+// version unless opts.Upgrade is set.
+func installEngine(ctx *app.AppContext, state *InstallState, eng engine.Engine, opts engine.InstallOptions) error {
+	name := eng.Name()
+	st := state.Engine(name)
+	wanted := engine.NormalizeVersionSpec(opts.Version)
+
+	if st.Installed && !opts.Upgrade {
+		switch {
+		case wanted == "":
+			fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("%s already installed", name)))
+			return nil
+		case wanted == st.Pin || wanted == "=="+st.Version:
+			fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("%s already at %s", name, st.Version)))
+			return nil
+		case st.Pin != "":
+			return fmt.Errorf("%s is pinned to %s (installed %s); re-run with --upgrade to install %s",
+				name, st.Pin, st.Version, wanted)
+		}
+	}
+
+	if err := setupVenv(ctx, eng.Env(), opts.Wheelhouse != ""); err != nil {
+		return err
+	}
+	st.EnvPath = eng.Env().Path
+
+	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Installing %s...", engine.RequirementFor(config.Engine(name), opts))))
+	if err := installWithProgress(ctx, eng, opts); err != nil {
+		fmt.Fprintln(ctx.Stdout, ui.Fail(fmt.Sprintf("%s installation failed: %s", name, err.Error())))
+		return err
+	}
+	if ctx.DryRun {
+		return nil
+	}
+
+	info := refreshEngineState(ctx, state, eng)
+	if !info.Installed {
+		fmt.Fprintln(ctx.Stdout, ui.Fail(fmt.Sprintf("%s installed but cannot be imported from %s", name, eng.Env().Python())))
+		return fmt.Errorf("%s not importable after install", name)
+	}
+	st.Pin = wanted
+	st.Extras = opts.Extras
+	st.ExtraIndexURLs = opts.ExtraIndexURLs
+	st.Constraints = opts.Constraints
+	fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("%s installed: %s", name, info.Version)))
+	fmt.Fprintln(ctx.Stdout, "    "+describeEnv(info))
 	return nil
 }
 
-func ensureUV(ctx *app.AppContext, state *InstallState) error {
-	if execx.CommandExists("uv") {
+// modeEngines expands an install mode into the engines it covers.
+func modeEngines(mode config.InstallMode) []config.Engine {
+	switch mode {
+	case config.InstallSGLang:
+		return []config.Engine{config.EngineSGLang}
+	case config.InstallVLLM:
+		return []config.Engine{config.EngineVLLM}
+	case config.InstallLMDeploy:
+		return []config.Engine{config.EngineLMDeploy}
+	case config.InstallBoth:
+		return []config.Engine{config.EngineSGLang, config.EngineVLLM}
+	default:
+		return nil
+	}
+}
+
+// parseExtras turns repeated engine=extra[,extra] values into a map.
+func parseExtras(values []string) (map[string][]string, error) {
+	extras := make(map[string][]string)
+	for _, v := range values {
+		name, list, ok := strings.Cut(v, "=")
+		if !ok || engine.Get(config.Engine(name)) == nil || list == "" {
+			return nil, fmt.Errorf("invalid --extras %q (use engine=extra[,extra])", v)
+		}
+		extras[name] = append(extras[name], strings.Split(list, ",")...)
+	}
+	return extras, nil
+}
+
+// ensureUV makes uv available on PATH. With uvBinary it copies that local
+// binary into ~/.local/bin; with offline it never reaches the network.
+func ensureUV(ctx *app.AppContext, state *InstallState, uvBinary string, offline bool) error {
+	if ctx.Runner.CommandExists("uv") {
 		state.UVInstalled = true
 		return nil
 	}
 
-	fmt.Fprintln(ctx.Stdout, ui.Info("Installing uv..."))
-	result := execx.Run(ctx.Ctx, "sh", "-c", "curl -LsSf https://astral.sh/uv/install.sh | sh")
-	if result.ExitCode != 0 {
-		return fmt.Errorf("failed to install uv: %s", result.Stderr)
+	switch {
+	case uvBinary != "":
+		fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Installing uv from %s...", uvBinary)))
+		dst := filepath.Join(os.Getenv("HOME"), ".local", "bin", "uv")
+		if ctx.DryRun {
+			ctx.Plan().Add(execx.Step{Kind: "exec", Command: []string{"install", "-D", "-m", "0755", uvBinary, dst}})
+		} else if err := copyFile(uvBinary, dst, 0755); err != nil {
+			return fmt.Errorf("failed to install uv: %w", err)
+		}
+	case offline:
+		return fmt.Errorf("uv not found; pass --uv-binary or use a bundle that contains bin/uv")
+	default:
+		fmt.Fprintln(ctx.Stdout, ui.Info("Installing uv..."))
+		// The installer downloads from GitHub; retry transient network errors.
+		result := execx.RunWith(ctx.Ctx, execx.Options{Timeout: 2 * time.Minute, Attempts: 3, Backoff: 2 * time.Second},
+			"sh", "-c", "curl -LsSf https://astral.sh/uv/install.sh | sh")
+		if result.Err != nil {
+			return fmt.Errorf("failed to install uv: %w", result.Err)
+		}
 	}
 
+	if ctx.DryRun {
+		state.UVInstalled = true
+		return nil
+	}
 	os.Setenv("PATH", os.Getenv("HOME")+"/.local/bin:"+os.Getenv("PATH"))
 
-	if execx.CommandExists("uv") {
+	if ctx.Runner.CommandExists("uv") {
 		state.UVInstalled = true
 		fmt.Fprintln(ctx.Stdout, ui.Ok("uv installed"))
 		return nil
@@ -199,22 +472,27 @@ func ensureUV(ctx *app.AppContext, state *InstallState) error {
 	return fmt.Errorf("uv installation failed - command not found after install")
 }
 
-func setupVenv(ctx *app.AppContext, venvDir string, state *InstallState) error {
-	absPath, _ := filepath.Abs(venvDir)
-
-	if _, err := os.Stat(venvDir); err == nil {
-		fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("venv exists: %s", absPath)))
-		state.VenvPath = absPath
+func setupVenv(ctx *app.AppContext, env engine.Env, offline bool) error {
+	if env.Exists() {
+		fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("venv exists: %s", env.Path)))
 		return nil
 	}
 
-	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Creating venv: %s", absPath)))
-	result := execx.Run(ctx.Ctx, "uv", "venv", venvDir)
+	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Creating venv: %s", env.Path)))
+	if !ctx.DryRun {
+		if err := os.MkdirAll(filepath.Dir(env.Path), 0755); err != nil {
+			return fmt.Errorf("failed to create venv: %w", err)
+		}
+	}
+	venvArgs := []string{"venv", env.Path}
+	if offline {
+		venvArgs = append(venvArgs, "--offline")
+	}
+	result := ctx.Runner.Run(ctx.Ctx, "uv", venvArgs...)
 	if result.ExitCode != 0 {
 		return fmt.Errorf("failed to create venv: %s", result.Stderr)
 	}
 
-	state.VenvPath = absPath
 	fmt.Fprintln(ctx.Stdout, ui.Ok("venv created"))
 	return nil
 }
@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+)
+
+func TestInstallDryRunReportsPinConflict(t *testing.T) {
+	isolateHome(t)
+	fakeCommand(t, "uv", "#!/bin/sh\necho 'uv 0.4.0'\n")
+	fakeEngineEnv(t, "vllm", "0.6.3")
+	state := &InstallState{}
+	state.Engine("vllm").Pin = "==0.6.3"
+	if err := saveState(state); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, out := newTestContextWith(t, app.GlobalFlags{NoColor: true, DryRun: true})
+	err := Install(ctx, []string{"--install", "vllm", "--vllm-version", "0.7.0"})
+	if err == nil || !strings.Contains(err.Error(), "pinned to ==0.6.3") {
+		t.Fatalf("dry-run install over a pin returned %v, want the pin conflict\n%s", err, out)
+	}
+	if steps := ctx.Plan().Steps; len(steps) != 0 {
+		t.Errorf("plan has %d steps after a failed install: %+v", len(steps), steps)
+	}
+}
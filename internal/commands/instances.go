@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/container"
+)
+
+// Instance is a server started by hermes. Instances are registered on start
+// so other commands can tell which engines are in use.
+type Instance struct {
+	ID        string    `json:"id"`
+	Engine    string    `json:"engine"`
+	Model     string    `json:"model"`
+	Host      string    `json:"host"`
+	Port      int       `json:"port"`
+	PID       int       `json:"pid"`
+	Daemon    bool      `json:"daemon"`
+	LogFile   string    `json:"log_file,omitempty"`
+	EnvPath   string    `json:"env_path,omitempty"`
+	Runtime   string    `json:"runtime,omitempty"`
+	Container string    `json:"container,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+func instancesDir() string {
+	return filepath.Join(filepath.Dir(getStateFilePath()), "instances")
+}
+
+func instanceID(cfg config.ServeConfig) string {
+	return fmt.Sprintf("%s-%d", cfg.Engine, cfg.Port)
+}
+
+// containerName is the name given to an instance's container.
+func containerName(id string) string {
+	return "hermes-" + id
+}
+
+// Running reports whether the instance still exists. Detached containers are
+// asked about through their runtime; everything else has a local process.
+func (i Instance) Running() bool {
+	if i.Container != "" && i.PID <= 0 {
+		return container.Runtime(i.Runtime).Running(context.Background(), i.Container)
+	}
+	if i.PID <= 0 {
+		return false
+	}
+	err := syscall.Kill(i.PID, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+func registerInstance(inst Instance) error {
+	if err := os.MkdirAll(instancesDir(), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(inst, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(instancesDir(), inst.ID+".json"), data, 0644)
+}
+
+func unregisterInstance(id string) {
+	os.Remove(filepath.Join(instancesDir(), id+".json"))
+}
+
+// listInstances returns the registered instances whose process is still
+// alive, removing stale entries as it goes.
+func listInstances() ([]Instance, error) {
+	entries, err := os.ReadDir(instancesDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []Instance
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(instancesDir(), entry.Name()))
+		if err != nil {
+			continue
+		}
+		var inst Instance
+		if err := json.Unmarshal(data, &inst); err != nil {
+			continue
+		}
+		if !inst.Running() {
+			unregisterInstance(inst.ID)
+			continue
+		}
+		instances = append(instances, inst)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	return instances, nil
+}
+
+// findInstance looks up a live instance by ID, returning nil if there is none.
+func findInstance(id string) (*Instance, error) {
+	all, err := listInstances()
+	if err != nil {
+		return nil, err
+	}
+	for _, inst := range all {
+		if inst.ID == id {
+			return &inst, nil
+		}
+	}
+	return nil, nil
+}
+
+// runningInstancesFor returns the live instances serving the given engine
+// from its managed environment. Containerised instances do not use it.
+func runningInstancesFor(name string) ([]Instance, error) {
+	all, err := listInstances()
+	if err != nil {
+		return nil, err
+	}
+	var matching []Instance
+	for _, inst := range all {
+		if inst.Engine == name && inst.Container == "" {
+			matching = append(matching, inst)
+		}
+	}
+	return matching, nil
+}
@@ -0,0 +1,217 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/engine"
+	"github.com/svngoku/hermes-cli/internal/ui"
+)
+
+func Uninstall(ctx *app.AppContext, args []string) error {
+	fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
+	force := fs.Bool("force", false, "Proceed even if an instance of the engine is running")
+	purge := fs.Bool("purge", false, "Delete the engine's whole managed environment")
+	fs.Usage = func() {
+		fmt.Fprintln(ctx.Stdout, "Usage: hermes uninstall <engine> [flags]")
+		fmt.Fprintln(ctx.Stdout)
+		fmt.Fprintln(ctx.Stdout, "Remove an engine from its managed environment")
+		fmt.Fprintln(ctx.Stdout)
+		fs.PrintDefaults()
+	}
+	name, err := parseEngineArg(fs, args)
+	if err != nil {
+		return err
+	}
+
+	state, _ := loadState()
+	eng := stateEngine(state, name)
+
+	fmt.Fprintln(ctx.Stdout, ui.Banner())
+	fmt.Fprintln(ctx.Stdout, ui.Step(fmt.Sprintf("Uninstalling %s...", name)))
+	fmt.Fprintln(ctx.Stdout, ui.HR())
+
+	if err := checkNotRunning(ctx, string(name), *force); err != nil {
+		return err
+	}
+
+	before, _ := eng.CheckInstalled(ctx.Ctx)
+	if !before.Installed && !*purge {
+		fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("%s is not installed in %s", name, eng.Env().Path)))
+		return nil
+	}
+
+	if *purge {
+		fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Removing environment %s", eng.Env().Path)))
+		if err := os.RemoveAll(eng.Env().Path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", eng.Env().Path, err)
+		}
+	} else if err := eng.Uninstall(ctx.Ctx); err != nil {
+		fmt.Fprintln(ctx.Stdout, ui.Fail(err.Error()))
+		return err
+	}
+
+	after := refreshEngineState(ctx, state, eng)
+	if *purge {
+		delete(state.Engines, string(name))
+	} else {
+		state.Engine(string(name)).Pin = ""
+	}
+	if err := saveState(state); err != nil {
+		ctx.Logger.Warn("failed to save state", "error", err)
+	}
+
+	printEnvDiff(ctx, before, after)
+	fmt.Fprintln(ctx.Stdout, ui.HR())
+	fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("%s uninstalled", name)))
+	return nil
+}
+
+func Upgrade(ctx *app.AppContext, args []string) error {
+	fs := flag.NewFlagSet("upgrade", flag.ExitOnError)
+	to := fs.String("to", "", "Target version or specifier (default: latest within the recorded pin)")
+	unpin := fs.Bool("unpin", false, "Drop the recorded version pin and upgrade to the latest release")
+	force := fs.Bool("force", false, "Proceed even if an instance of the engine is running")
+	fs.Usage = func() {
+		fmt.Fprintln(ctx.Stdout, "Usage: hermes upgrade <engine> [flags]")
+		fmt.Fprintln(ctx.Stdout)
+		fmt.Fprintln(ctx.Stdout, "Upgrade an engine in its managed environment")
+		fmt.Fprintln(ctx.Stdout)
+		fs.PrintDefaults()
+	}
+	name, err := parseEngineArg(fs, args)
+	if err != nil {
+		return err
+	}
+	if *to != "" && *unpin {
+		return fmt.Errorf("--to and --unpin cannot be combined")
+	}
+
+	state, _ := loadState()
+	eng := stateEngine(state, name)
+
+	fmt.Fprintln(ctx.Stdout, ui.Banner())
+	fmt.Fprintln(ctx.Stdout, ui.Step(fmt.Sprintf("Upgrading %s...", name)))
+	fmt.Fprintln(ctx.Stdout, ui.HR())
+
+	if err := checkNotRunning(ctx, string(name), *force); err != nil {
+		return err
+	}
+
+	before := refreshEngineState(ctx, state, eng)
+	if !before.Installed {
+		return fmt.Errorf("%s is not installed (run: hermes install --install %s)", name, name)
+	}
+
+	// Keep the resolution inputs the engine was originally installed with.
+	st := state.Engine(string(name))
+	version := *to
+	if version == "" && !*unpin {
+		// Stay within the pin the user chose; --to or --unpin moves past it.
+		version = st.Pin
+	}
+	if version != "" && version == st.Pin {
+		fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Keeping pin %s (use --unpin or --to to change it)", st.Pin)))
+	}
+	opts := engine.InstallOptions{
+		Version:        version,
+		Extras:         st.Extras,
+		ExtraIndexURLs: st.ExtraIndexURLs,
+		Constraints:    st.Constraints,
+		Upgrade:        true,
+	}
+	err = installEngine(ctx, state, eng, opts)
+	if saveErr := saveState(state); saveErr != nil {
+		ctx.Logger.Warn("failed to save state", "error", saveErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	after, _ := eng.CheckInstalled(ctx.Ctx)
+	fmt.Fprintln(ctx.Stdout, ui.HR())
+	printEnvDiff(ctx, before, after)
+	return nil
+}
+
+// parseEngineArg accepts the engine name either before or after the flags.
+func parseEngineArg(fs *flag.FlagSet, args []string) (config.Engine, error) {
+	positional, err := parseTargetArg(fs, args)
+	if err != nil {
+		return "", err
+	}
+	if positional == "" {
+		fs.Usage()
+		return "", fmt.Errorf("engine name is required")
+	}
+	name := config.Engine(positional)
+	if engine.Get(name) == nil {
+		return "", fmt.Errorf("invalid engine: %s (use sglang, vllm or lmdeploy)", positional)
+	}
+	return name, nil
+}
+
+// checkNotRunning refuses to touch an engine that is serving traffic.
+func checkNotRunning(ctx *app.AppContext, name string, force bool) error {
+	running, err := runningInstancesFor(name)
+	if err != nil {
+		ctx.Logger.Warn("failed to list instances", "error", err)
+	}
+	if len(running) == 0 {
+		return nil
+	}
+	for _, inst := range running {
+		msg := fmt.Sprintf("%s is running (pid=%d, port=%d, model=%s)", inst.ID, inst.PID, inst.Port, inst.Model)
+		if force {
+			fmt.Fprintln(ctx.Stdout, ui.Warn(msg))
+		} else {
+			fmt.Fprintln(ctx.Stdout, ui.Fail(msg))
+		}
+	}
+	if force {
+		return nil
+	}
+	return fmt.Errorf("%d running instance(s) use %s; stop them or pass --force", len(running), name)
+}
+
+// refreshEngineState re-reads the engine's environment and records it.
+func refreshEngineState(ctx *app.AppContext, state *InstallState, eng engine.Engine) engine.EnvInfo {
+	info, err := eng.CheckInstalled(ctx.Ctx)
+	if err != nil {
+		ctx.Logger.Debug("engine inspection failed", "engine", eng.Name(), "error", err)
+	}
+	st := state.Engine(eng.Name())
+	st.Installed = info.Installed
+	st.Version = info.Version
+	st.TorchVersion = info.TorchVersion
+	st.TorchCUDA = info.TorchCUDA
+	return info
+}
+
+// printEnvDiff shows how the packages hermes cares about changed.
+func printEnvDiff(ctx *app.AppContext, before, after engine.EnvInfo) {
+	rows := []struct {
+		name          string
+		before, after string
+	}{
+		{before.Name, before.Version, after.Version},
+		{"torch", before.TorchVersion, after.TorchVersion},
+		{"torch cuda", before.TorchCUDA, after.TorchCUDA},
+		{"flashinfer", before.FlashInfer, after.FlashInfer},
+		{"triton", before.Triton, after.Triton},
+	}
+	for _, r := range rows {
+		if r.before == "" && r.after == "" {
+			continue
+		}
+		line := fmt.Sprintf("%-11s %s → %s", r.name, orDash(r.before), orDash(r.after))
+		if r.before == r.after {
+			fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("%-11s %s (unchanged)", r.name, r.after)))
+		} else {
+			fmt.Fprintln(ctx.Stdout, ui.Ok(line))
+		}
+	}
+}
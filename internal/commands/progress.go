@@ -0,0 +1,217 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/engine"
+	"github.com/svngoku/hermes-cli/internal/execx"
+	"github.com/svngoku/hermes-cli/internal/ui"
+	"github.com/svngoku/hermes-cli/internal/ui/tui"
+)
+
+type installPhase int
+
+const (
+	phaseResolving installPhase = iota
+	phaseDownloading
+	phaseBuilding
+	phaseInstalling
+)
+
+var installPhaseNames = []string{"Resolving", "Downloading", "Building", "Installing"}
+
+const (
+	installTailLines    = 200
+	installErrorContext = 25
+)
+
+// installProgress consumes uv's output line by line, copies it to the log
+// and turns it into phase updates for the progress display.
+type installProgress struct {
+	mu     sync.Mutex
+	log    io.Writer
+	report func(phase installPhase, status tui.StepStatus, detail string)
+	phase  installPhase
+	tail   *execx.Tail
+}
+
+func newInstallProgress(log io.Writer, report func(installPhase, tui.StepStatus, string)) *installProgress {
+	return &installProgress{log: log, report: report, tail: execx.NewTail(installTailLines)}
+}
+
+// onLine is the engine.InstallOptions.OnLine callback.
+func (p *installProgress) onLine(l execx.Line) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	io.WriteString(p.log, execx.FormatLine(l, "uv: ", true))
+	p.handleLine(l)
+}
+
+func (p *installProgress) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report(phaseResolving, tui.StepRunning, "")
+}
+
+func (p *installProgress) handleLine(l execx.Line) {
+	line := strings.TrimSpace(l.Text)
+	if line == "" {
+		return
+	}
+	l.Text = line
+	p.tail.Add(l)
+
+	phase, detail, ok := classifyUVLine(line)
+	if !ok {
+		return
+	}
+	p.advance(phase, detail)
+}
+
+// advance moves the display to phase, completing the current phase and
+// marking any phase that uv never entered as skipped.
+func (p *installProgress) advance(phase installPhase, detail string) {
+	if phase < p.phase {
+		return
+	}
+	if phase > p.phase {
+		p.report(p.phase, tui.StepDone, "")
+		for skipped := p.phase + 1; skipped < phase; skipped++ {
+			p.report(skipped, tui.StepSkipped, "not needed")
+		}
+		p.phase = phase
+	}
+	p.report(phase, tui.StepRunning, detail)
+}
+
+// finish settles every phase once uv has exited.
+func (p *installProgress) finish(ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !ok {
+		p.report(p.phase, tui.StepFailed, "")
+		return
+	}
+	p.report(p.phase, tui.StepDone, "")
+	for rest := p.phase + 1; rest <= phaseInstalling; rest++ {
+		p.report(rest, tui.StepSkipped, "up to date")
+	}
+}
+
+// errorSection returns the part of the output that explains a failure:
+// from uv's first error marker onwards, or the last lines if there is none.
+func (p *installProgress) errorSection() []string {
+	var lines []string
+	for _, l := range p.tail.Lines() {
+		lines = append(lines, l.Text)
+	}
+	for i, line := range lines {
+		if strings.HasPrefix(line, "error:") || strings.HasPrefix(line, "×") {
+			end := i + installErrorContext
+			if end > len(lines) {
+				end = len(lines)
+			}
+			return lines[i:end]
+		}
+	}
+	start := len(lines) - installErrorContext
+	if start < 0 {
+		start = 0
+	}
+	return lines[start:]
+}
+
+// classifyUVLine maps a line of `uv pip install` output to a phase.
+func classifyUVLine(line string) (installPhase, string, bool) {
+	word, rest, _ := strings.Cut(line, " ")
+	switch word {
+	case "Resolved":
+		return phaseDownloading, rest, true
+	case "Downloading", "Downloaded", "Prepared":
+		return phaseDownloading, rest, true
+	case "Building", "Built":
+		return phaseBuilding, rest, true
+	case "Installed", "Uninstalled", "Audited", "+", "-":
+		return phaseInstalling, rest, true
+	}
+	return 0, "", false
+}
+
+// installWithProgress runs eng.Install with its output streamed: as a live
+// step list on a terminal, as one line per phase otherwise. The raw output
+// always goes to the log file; on failure only the relevant section is shown.
+func installWithProgress(ctx *app.AppContext, eng engine.Engine, opts engine.InstallOptions) error {
+	var err error
+	var progress *installProgress
+
+	if ctx.DryRun {
+		return eng.Install(ctx.Ctx, opts)
+	}
+
+	if ui.IsTerminal(ctx.Stdout) {
+		progress, err = installWithSteps(ctx, eng, opts)
+	} else {
+		name := eng.Name()
+		announced := installPhase(-1)
+		progress = newInstallProgress(ctx.LogSink(), func(phase installPhase, status tui.StepStatus, detail string) {
+			if status == tui.StepRunning && phase != announced {
+				announced = phase
+				fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("%s: %s...", name, strings.ToLower(installPhaseNames[phase]))))
+			}
+		})
+		progress.start()
+		opts.OnLine = progress.onLine
+		err = eng.Install(ctx.Ctx, opts)
+		progress.finish(err == nil)
+	}
+
+	if err != nil {
+		for _, line := range progress.errorSection() {
+			fmt.Fprintln(ctx.Stdout, "    "+line)
+		}
+		if ctx.LogFile != "" {
+			fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Full installer output: %s", ctx.LogFile)))
+		}
+	}
+	return err
+}
+
+func installWithSteps(ctx *app.AppContext, eng engine.Engine, opts engine.InstallOptions) (*installProgress, error) {
+	model := tui.NewStepsModel(installPhaseNames).WithTitle("Installing " + eng.Name())
+	program := tea.NewProgram(model, tea.WithOutput(ctx.Stdout))
+
+	progress := newInstallProgress(ctx.LogSink(), func(phase installPhase, status tui.StepStatus, detail string) {
+		program.Send(tui.StepUpdateMsg{Index: int(phase), Status: status, Detail: detail})
+	})
+
+	installCtx, cancel := context.WithCancel(ctx.Ctx)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		progress.start()
+		opts.OnLine = progress.onLine
+		err := eng.Install(installCtx, opts)
+		progress.finish(err == nil)
+		program.Send(tui.AllDoneMsg{})
+		errCh <- err
+	}()
+
+	if _, err := program.Run(); err != nil {
+		cancel()
+		<-errCh
+		return progress, err
+	}
+	fmt.Fprintln(ctx.Stdout)
+
+	// The program also returns when the user quits; stop the installer then.
+	cancel()
+	return progress, <-errCh
+}
@@ -0,0 +1,279 @@
+package commands
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/container"
+	"github.com/svngoku/hermes-cli/internal/ui"
+)
+
+func Ps(ctx *app.AppContext, args []string) error {
+	fs := flag.NewFlagSet("ps", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Output in JSON format")
+	fs.Usage = func() {
+		fmt.Fprintln(ctx.Stdout, "Usage: hermes ps [flags]")
+		fmt.Fprintln(ctx.Stdout)
+		fmt.Fprintln(ctx.Stdout, "List servers started by hermes")
+		fmt.Fprintln(ctx.Stdout)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	instances, err := listInstances()
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		if instances == nil {
+			instances = []Instance{}
+		}
+		enc := json.NewEncoder(ctx.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(instances)
+	}
+
+	if len(instances) == 0 {
+		fmt.Fprintln(ctx.Stdout, ui.Info("No running instances"))
+		return nil
+	}
+
+	rows := [][]string{{"ID", "ENGINE", "MODEL", "ENDPOINT", "RUNTIME", "PROCESS", "UPTIME"}}
+	for _, inst := range instances {
+		runtime, process := "venv", strconv.Itoa(inst.PID)
+		if inst.Container != "" {
+			runtime, process = inst.Runtime, inst.Container
+		}
+		rows = append(rows, []string{
+			inst.ID,
+			inst.Engine,
+			inst.Model,
+			fmt.Sprintf("%s:%d", inst.Host, inst.Port),
+			runtime,
+			process,
+			time.Since(inst.StartedAt).Round(time.Second).String(),
+		})
+	}
+	printTable(ctx, rows)
+	return nil
+}
+
+func Stop(ctx *app.AppContext, args []string) error {
+	fs := flag.NewFlagSet("stop", flag.ExitOnError)
+	all := fs.Bool("all", false, "Stop every instance (or every instance of the given engine)")
+	timeout := fs.Int("timeout", 30, "Seconds to wait for a graceful shutdown before killing")
+	fs.Usage = func() {
+		fmt.Fprintln(ctx.Stdout, "Usage: hermes stop <id|engine> [flags]")
+		fmt.Fprintln(ctx.Stdout)
+		fmt.Fprintln(ctx.Stdout, "Stop servers started by hermes (see 'hermes ps' for IDs)")
+		fmt.Fprintln(ctx.Stdout)
+		fs.PrintDefaults()
+	}
+	target, err := parseTargetArg(fs, args)
+	if err != nil {
+		return err
+	}
+	if target == "" && !*all {
+		fs.Usage()
+		return fmt.Errorf("instance ID or engine is required")
+	}
+
+	targets, err := resolveInstances(target, *all)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, inst := range targets {
+		if err := stopInstance(ctx, inst, time.Duration(*timeout)*time.Second); err != nil {
+			fmt.Fprintln(ctx.Stdout, ui.Fail(fmt.Sprintf("%s: %s", inst.ID, err)))
+			failed++
+			continue
+		}
+		unregisterInstance(inst.ID)
+		fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("%s stopped", inst.ID)))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d instance(s) could not be stopped", failed)
+	}
+	return nil
+}
+
+// stopInstance asks an instance to shut down and waits for it, escalating to
+// SIGKILL for processes that outlive the timeout.
+func stopInstance(ctx *app.AppContext, inst Instance, timeout time.Duration) error {
+	if inst.Container != "" {
+		rt := container.Runtime(inst.Runtime)
+		err := rt.Stop(ctx.Ctx, inst.Container, int(timeout.Seconds()))
+		if err == nil || inst.PID <= 0 {
+			if inst.Daemon {
+				rt.Remove(ctx.Ctx, inst.Container)
+			}
+			return err
+		}
+		// The container may not exist yet while its image is pulled; fall
+		// back to signalling the attached runtime client.
+		ctx.Logger.Debug("container stop failed, signalling client", "error", err)
+	}
+
+	// Daemons lead their own process group, which takes workers with them.
+	pid := inst.PID
+	if inst.Daemon {
+		pid = -pid
+	}
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !inst.Running() {
+			return nil
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	ctx.Logger.Warn("graceful shutdown timed out, killing", "instance", inst.ID)
+	if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
+
+func Logs(ctx *app.AppContext, args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	follow := fs.Bool("f", false, "Follow log output")
+	tail := fs.Int("tail", 100, "Number of lines to show from the end (-1 for all)")
+	fs.Usage = func() {
+		fmt.Fprintln(ctx.Stdout, "Usage: hermes logs <id|engine> [flags]")
+		fmt.Fprintln(ctx.Stdout)
+		fmt.Fprintln(ctx.Stdout, "Show the output of a server started by hermes")
+		fmt.Fprintln(ctx.Stdout)
+		fs.PrintDefaults()
+	}
+	target, err := parseTargetArg(fs, args)
+	if err != nil {
+		return err
+	}
+	if target == "" {
+		fs.Usage()
+		return fmt.Errorf("instance ID or engine is required")
+	}
+
+	targets, err := resolveInstances(target, false)
+	if err != nil {
+		return err
+	}
+	inst := targets[0]
+
+	if inst.Container != "" && inst.Daemon {
+		rt := container.Runtime(inst.Runtime)
+		return ctx.Runner.RunWithStreaming(ctx.Ctx, ctx.Stdout, ctx.Stderr, string(rt), rt.LogsArgs(inst.Container, *follow, *tail)...)
+	}
+	if inst.LogFile == "" {
+		return fmt.Errorf("%s was started without a log file", inst.ID)
+	}
+	return tailFile(ctx, inst.LogFile, *tail, *follow)
+}
+
+// parseTargetArg accepts the target either before or after the flags.
+func parseTargetArg(fs *flag.FlagSet, args []string) (string, error) {
+	var positional string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		positional, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if positional == "" {
+		positional = fs.Arg(0)
+	}
+	return positional, nil
+}
+
+// resolveInstances matches target against instance IDs, then engine names.
+// An engine with several instances needs `all` to select them together.
+func resolveInstances(target string, all bool) ([]Instance, error) {
+	instances, err := listInstances()
+	if err != nil {
+		return nil, err
+	}
+	if target == "" {
+		if len(instances) == 0 {
+			return nil, fmt.Errorf("no running instances")
+		}
+		return instances, nil
+	}
+
+	var matches []Instance
+	for _, inst := range instances {
+		if inst.ID == target {
+			return []Instance{inst}, nil
+		}
+		if inst.Engine == target {
+			matches = append(matches, inst)
+		}
+	}
+	switch {
+	case len(matches) == 0:
+		return nil, fmt.Errorf("no running instance matches %q (see 'hermes ps')", target)
+	case len(matches) > 1 && !all:
+		ids := make([]string, len(matches))
+		for i, inst := range matches {
+			ids[i] = inst.ID
+		}
+		return nil, fmt.Errorf("%q matches several instances (%s); pass an ID or --all", target, strings.Join(ids, ", "))
+	}
+	return matches, nil
+}
+
+// tailFile prints the last n lines of path and, when following, anything
+// appended afterwards until the command is interrupted.
+func tailFile(ctx *app.AppContext, path string, n int, follow bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	if n >= 0 {
+		lines := strings.SplitAfter(string(data), "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		if len(lines) > n {
+			lines = lines[len(lines)-n:]
+		}
+		data = []byte(strings.Join(lines, ""))
+	}
+	ctx.Stdout.Write(data)
+
+	if !follow {
+		return nil
+	}
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := io.Copy(ctx.Stdout, f); err != nil {
+				return err
+			}
+		}
+	}
+}
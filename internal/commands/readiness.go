@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/engine"
+	"github.com/svngoku/hermes-cli/internal/execx"
+	"github.com/svngoku/hermes-cli/internal/ui"
+)
+
+const defaultProbeTimeout = 5 * time.Second
+
+type ProbeResult struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	Passed     bool   `json:"passed"`
+	Attempts   int    `json:"attempts"`
+	DurationMs int64  `json:"duration_ms"`
+	Message    string `json:"message,omitempty"`
+}
+
+// waitForReadiness polls each probe in order until it passes, then moves on
+// to the next one. The returned results record how long every stage took.
+func waitForReadiness(ctx *app.AppContext, base string, probes []engine.Probe, timeout time.Duration) ([]ProbeResult, error) {
+	if ctx.DryRun {
+		planProbes(ctx, "readiness", base, probes)
+		return nil, nil
+	}
+	deadline := time.Now().Add(timeout)
+	checkInterval := 2 * time.Second
+	results := make([]ProbeResult, 0, len(probes))
+
+	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Waiting for server at %s (timeout: %s)", base, timeout)))
+
+	start := time.Now()
+	for _, probe := range probes {
+		stageStart := time.Now()
+		result := ProbeResult{Name: probe.Name, Path: probe.Path}
+
+		for {
+			result.Attempts++
+			// A slow probe, such as a first generation, must not carry the
+			// wait past the deadline.
+			probeCtx, cancel := context.WithDeadline(ctx.Ctx, deadline)
+			err := runProbe(probeCtx, base, probe)
+			cancel()
+			if err == nil {
+				result.Passed = true
+				result.Message = ""
+				break
+			}
+			result.Message = err.Error()
+			if !time.Now().Add(checkInterval).Before(deadline) {
+				break
+			}
+			select {
+			case <-ctx.Ctx.Done():
+				return append(results, result), ctx.Ctx.Err()
+			case <-time.After(checkInterval):
+			}
+		}
+
+		result.DurationMs = time.Since(stageStart).Milliseconds()
+		results = append(results, result)
+
+		if !result.Passed {
+			fmt.Fprintln(ctx.Stdout, ui.Fail(fmt.Sprintf("%s %s: %s (%d attempts)", probe.Name, probe.Path, result.Message, result.Attempts)))
+			return results, fmt.Errorf("timeout waiting for server readiness at probe %q", probe.Name)
+		}
+		fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("%s %s: ready after %s", probe.Name, probe.Path, formatMs(result.DurationMs))))
+	}
+
+	fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("Server is ready (%s)", time.Since(start).Round(time.Millisecond))))
+	return results, nil
+}
+
+// checkProbes runs each probe exactly once without waiting.
+func checkProbes(ctx context.Context, base string, probes []engine.Probe) []ProbeResult {
+	results := make([]ProbeResult, 0, len(probes))
+	for _, probe := range probes {
+		start := time.Now()
+		err := runProbe(ctx, base, probe)
+		result := ProbeResult{
+			Name:       probe.Name,
+			Path:       probe.Path,
+			Passed:     err == nil,
+			Attempts:   1,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			result.Message = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func runProbe(ctx context.Context, base string, probe engine.Probe) error {
+	timeout := probe.Timeout
+	if timeout == 0 {
+		timeout = defaultProbeTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	method := probe.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if probe.Body != "" {
+		body = strings.NewReader(probe.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, base+probe.Path, body)
+	if err != nil {
+		return err
+	}
+	if probe.Body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	expect := probe.ExpectStatus
+	if expect == 0 {
+		expect = http.StatusOK
+	}
+	if resp.StatusCode != expect {
+		return fmt.Errorf("status %d, want %d", resp.StatusCode, expect)
+	}
+	if probe.ExpectBody != "" {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(data), probe.ExpectBody) {
+			return fmt.Errorf("response does not contain %q", probe.ExpectBody)
+		}
+	}
+	return nil
+}
+
+func formatMs(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// planProbes adds probes to the dry-run plan, in the order they would run.
+func planProbes(ctx *app.AppContext, stage, base string, probes []engine.Probe) {
+	for _, p := range probes {
+		ctx.Plan().Add(execx.Step{Kind: "probe", Method: p.Method, URL: base + p.Path, Body: p.Body, Note: stage + ": " + p.Name})
+	}
+}
@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/gpu"
+	"github.com/svngoku/hermes-cli/internal/ui"
+)
+
+// addResourceFlags registers the process controls shared by serve and run.
+func addResourceFlags(fs *flag.FlagSet, res *config.Resources) {
+	fs.StringVar(&res.NoFile, "nofile", "", "Open-file limit for the engine (number or unlimited)")
+	fs.StringVar(&res.MemLock, "memlock", "", "Locked-memory limit for the engine in bytes (number or unlimited)")
+	fs.StringVar(&res.Core, "core", "", "Core-dump size limit for the engine (number or unlimited)")
+	fs.IntVar(&res.Nice, "nice", 0, "Scheduling niceness for the engine (-20..19)")
+	fs.StringVar(&res.IONice, "ionice", "", "I/O scheduling class: idle|best-effort[:0-7]|realtime[:0-7]")
+	fs.StringVar(&res.CPUs, "cpuset", "", "CPUs to run the engine on, as a cpulist (0-15,32-47) or auto for the GPUs' local CPUs")
+	fs.StringVar(&res.NUMA, "numa", "", "NUMA nodes to bind the engine's CPUs and memory to, or auto for the GPUs' nodes")
+}
+
+// rlimit is one limit settable with prlimit(1) and docker --ulimit.
+type rlimit struct {
+	name     string
+	resource int
+	value    func(config.Resources) string
+}
+
+var rlimits = []rlimit{
+	{"nofile", unix.RLIMIT_NOFILE, func(r config.Resources) string { return r.NoFile }},
+	{"memlock", unix.RLIMIT_MEMLOCK, func(r config.Resources) string { return r.MemLock }},
+	{"core", unix.RLIMIT_CORE, func(r config.Resources) string { return r.Core }},
+}
+
+// parseLimit returns the limit value, with unlimited as RLIM_INFINITY.
+func parseLimit(s string) (uint64, error) {
+	if s == "unlimited" {
+		return unix.RLIM_INFINITY, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// resolveResources validates res and expands auto CPU and NUMA placement
+// from the topology of the GPUs the server will use.
+func resolveResources(ctx *app.AppContext, cfg config.ServeConfig) (config.Resources, error) {
+	res := cfg.Resources
+	for _, l := range rlimits {
+		if v := l.value(res); v != "" {
+			if _, err := parseLimit(v); err != nil {
+				return res, fmt.Errorf("invalid --%s %q (use a number or unlimited)", l.name, v)
+			}
+		}
+	}
+	if res.Nice < -20 || res.Nice > 19 {
+		return res, fmt.Errorf("invalid --nice %d (use -20..19)", res.Nice)
+	}
+	if res.IONice != "" {
+		if _, err := ioniceArgs(res.IONice); err != nil {
+			return res, err
+		}
+	}
+	for flagName, v := range map[string]string{"cpuset": res.CPUs, "numa": res.NUMA} {
+		if v != "" && v != "auto" && len(gpu.ParseCPUList(v)) == 0 {
+			return res, fmt.Errorf("invalid --%s %q (use a list such as 0-15,32-47, or auto)", flagName, v)
+		}
+	}
+
+	if res.CPUs != "auto" && res.NUMA != "auto" {
+		return res, nil
+	}
+	topo, err := gpu.LoadTopology(ctx.Ctx)
+	if err != nil {
+		return res, fmt.Errorf("auto placement needs the GPU topology: %w", err)
+	}
+	cpus, nodes := topo.Affinity(gpu.ParseCPUList(cfg.GPUs))
+	if res.CPUs == "auto" {
+		res.CPUs = cpus
+	}
+	if res.NUMA == "auto" {
+		res.NUMA = nodes
+	}
+	if cpus == "" && nodes == "" {
+		fmt.Fprintln(ctx.Stdout, ui.Warn("GPU topology reports no CPU affinity; placement left unchanged"))
+	} else {
+		fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("GPU-local CPUs: %s (NUMA %s)", orDash(cpus), orDash(nodes))))
+	}
+	return res, nil
+}
+
+// resolveGPUs expands --gpus auto to the TP group doctor would recommend:
+// the best-connected GPUs, idle ones first.
+func resolveGPUs(ctx *app.AppContext, cfg config.ServeConfig) (string, error) {
+	if cfg.GPUs != "auto" {
+		return cfg.GPUs, nil
+	}
+	topo, err := gpu.LoadTopology(ctx.Ctx)
+	if err != nil {
+		return "", fmt.Errorf("--gpus auto needs the GPU topology: %w", err)
+	}
+	inv, err := gpu.LoadInventory(ctx.Ctx)
+	if err != nil {
+		ctx.Logger.Warn("GPU inventory unavailable; busy GPUs may be selected", "error", err)
+		inv = nil
+	}
+	group, err := recommendGPUs(topo, inv, cfg.TP)
+	if err != nil {
+		return "", fmt.Errorf("--gpus auto for --tp %d: %w", cfg.TP, err)
+	}
+	if group.Busy {
+		fmt.Fprintln(ctx.Stdout, ui.Warn(fmt.Sprintf("Fewer than %d idle GPUs; selected GPUs include ones in use", cfg.TP)))
+	}
+	return joinInts(group.GPUs), nil
+}
+
+// checkResourcePrivileges fails early for settings only root may apply,
+// rather than leaving the launcher to fail after the banner.
+func checkResourcePrivileges(res config.Resources) error {
+	if os.Geteuid() == 0 {
+		return nil
+	}
+	for _, l := range rlimits {
+		v := l.value(res)
+		if v == "" {
+			continue
+		}
+		want, _ := parseLimit(v)
+		var cur unix.Rlimit
+		if err := unix.Getrlimit(l.resource, &cur); err != nil {
+			continue
+		}
+		if want > cur.Max {
+			return fmt.Errorf("--%s %s exceeds the hard limit %s; raise it in /etc/security/limits.conf or run as root",
+				l.name, v, formatLimit(cur.Max))
+		}
+	}
+	if res.Nice < 0 {
+		return fmt.Errorf("--nice %d needs root", res.Nice)
+	}
+	if strings.HasPrefix(res.IONice, "realtime") {
+		return fmt.Errorf("--ionice realtime needs root")
+	}
+	return nil
+}
+
+func formatLimit(v uint64) string {
+	if v == unix.RLIM_INFINITY {
+		return "unlimited"
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+func ioniceArgs(spec string) ([]string, error) {
+	class, level, hasLevel := strings.Cut(spec, ":")
+	classes := map[string]string{"realtime": "1", "best-effort": "2", "idle": "3"}
+	c, ok := classes[class]
+	if !ok {
+		return nil, fmt.Errorf("invalid --ionice %q (use idle, best-effort[:0-7] or realtime[:0-7])", spec)
+	}
+	args := []string{"-c", c}
+	if hasLevel {
+		n, err := strconv.Atoi(level)
+		if err != nil || n < 0 || n > 7 || class == "idle" {
+			return nil, fmt.Errorf("invalid --ionice %q (levels 0-7 apply to best-effort and realtime)", spec)
+		}
+		args = append(args, "-n", level)
+	}
+	return args, nil
+}
+
+// resourceCommand wraps the engine command in the launchers that apply res.
+// Each launcher execs the next, so the engine and every worker it spawns
+// inherit the limits, priority and placement.
+func resourceCommand(ctx *app.AppContext, res config.Resources, name string, args []string) (string, []string, error) {
+	var prefix []string
+	use := func(tool, pkg string, toolArgs ...string) error {
+		if !ctx.Runner.CommandExists(tool) {
+			return fmt.Errorf("%s not found in PATH (install %s)", tool, pkg)
+		}
+		prefix = append(prefix, tool)
+		prefix = append(prefix, toolArgs...)
+		return nil
+	}
+
+	var limits []string
+	for _, l := range rlimits {
+		v := l.value(res)
+		if v == "" {
+			continue
+		}
+		// A soft limit within the hard limit needs no privilege; otherwise
+		// both are raised, which checkResourcePrivileges allowed.
+		want, _ := parseLimit(v)
+		var cur unix.Rlimit
+		if unix.Getrlimit(l.resource, &cur) == nil && want <= cur.Max {
+			limits = append(limits, fmt.Sprintf("--%s=%s:", l.name, v))
+		} else {
+			limits = append(limits, fmt.Sprintf("--%s=%s:%s", l.name, v, v))
+		}
+	}
+	if len(limits) > 0 {
+		if err := use("prlimit", "util-linux", limits...); err != nil {
+			return "", nil, err
+		}
+	}
+	if res.Nice != 0 {
+		if err := use("nice", "coreutils", "-n", strconv.Itoa(res.Nice)); err != nil {
+			return "", nil, err
+		}
+	}
+	if res.IONice != "" {
+		ionice, _ := ioniceArgs(res.IONice)
+		if err := use("ionice", "util-linux", ionice...); err != nil {
+			return "", nil, err
+		}
+	}
+	switch {
+	case res.NUMA != "":
+		numa := []string{"--cpunodebind=" + res.NUMA, "--membind=" + res.NUMA}
+		if res.CPUs != "" {
+			numa = append(numa, "--physcpubind="+res.CPUs)
+		}
+		if err := use("numactl", "numactl", numa...); err != nil {
+			return "", nil, err
+		}
+	case res.CPUs != "":
+		if err := use("taskset", "util-linux", "-c", res.CPUs); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if len(prefix) == 0 {
+		return name, args, nil
+	}
+	return prefix[0], append(append(prefix[1:], name), args...), nil
+}
+
+// containerUlimits maps res to `--ulimit` values; containers take both soft
+// and hard limits, with -1 for unlimited.
+func containerUlimits(res config.Resources) []string {
+	var ulimits []string
+	for _, l := range rlimits {
+		v := l.value(res)
+		if v == "" {
+			continue
+		}
+		if v == "unlimited" {
+			v = "-1"
+		}
+		ulimits = append(ulimits, fmt.Sprintf("%s=%s:%s", l.name, v, v))
+	}
+	return ulimits
+}
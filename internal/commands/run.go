@@ -3,32 +3,43 @@ package commands
 import (
 	"flag"
 	"fmt"
-	"net/http"
+	"slices"
 	"time"
 
 	"github.com/svngoku/hermes-cli/internal/app"
 	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/engine"
 	"github.com/svngoku/hermes-cli/internal/ui"
 )
 
 func Run(ctx *app.AppContext, args []string) error {
 	fs := flag.NewFlagSet("run", flag.ExitOnError)
-	engineName := fs.String("engine", "", "Engine: sglang|vllm (required)")
+	engineName := fs.String("engine", "", "Engine: sglang|vllm|lmdeploy (required)")
 	model := fs.String("model", "", "Model path or HuggingFace repo (required)")
 	tp := fs.Int("tp", 4, "Tensor parallel size")
 	host := fs.String("host", "0.0.0.0", "Bind host")
 	port := fs.Int("port", 30000, "Bind port")
 	daemon := fs.Bool("daemon", false, "Run in daemon mode")
-	installMode := fs.String("install", "both", "Install mode: sglang|vllm|both|none")
+	installMode := fs.String("install", "", "Install mode: sglang|vllm|lmdeploy|both|none (default: the --engine)")
 	noVerify := fs.Bool("no-verify", false, "Skip verification")
 	extraArgs := fs.String("extra-args", "", "Additional engine arguments")
+	backend := fs.String("backend", "", "lmdeploy backend: turbomind|pytorch")
+	quantization := fs.String("quantization", "", "Quantization method (see 'hermes engines')")
+	toolCallParser := fs.String("tool-call-parser", "", "Tool-call parser (see 'hermes engines')")
 	readinessTimeout := fs.Int("readiness-timeout", 300, "Readiness check timeout in seconds")
+	gpus := fs.String("gpus", "", "Comma-separated GPU indexes to use, or auto for the best-connected idle GPUs (default: all)")
+	runtime := fs.String("runtime", "", "Run the engine's image with docker|podman (skips install)")
+	image := fs.String("image", "", "Container image (default: the engine's official image)")
+	shmSize := fs.String("shm-size", "16g", "Container shared memory size")
+	var resources config.Resources
+	addResourceFlags(fs, &resources)
 	fs.Usage = func() {
 		fmt.Fprintln(ctx.Stdout, "Usage: hermes run [flags]")
 		fmt.Fprintln(ctx.Stdout)
 		fmt.Fprintln(ctx.Stdout, "Run full pipeline: doctor → install → serve → verify")
 		fmt.Fprintln(ctx.Stdout)
 		fs.PrintDefaults()
+		printEngineOptions(ctx)
 	}
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -44,10 +55,49 @@ func Run(ctx *app.AppContext, args []string) error {
 		eng = config.EngineSGLang
 	case "vllm":
 		eng = config.EngineVLLM
+	case "lmdeploy":
+		eng = config.EngineLMDeploy
 	default:
-		return fmt.Errorf("invalid engine: %s (use sglang or vllm)", *engineName)
+		return fmt.Errorf("invalid engine: %s (use sglang, vllm or lmdeploy)", *engineName)
 	}
 
+	if *backend != "" && *backend != "turbomind" && *backend != "pytorch" {
+		return fmt.Errorf("invalid backend: %s (use turbomind or pytorch)", *backend)
+	}
+
+	mode := config.InstallMode(*installMode)
+	if mode == "" {
+		mode = config.InstallMode(eng)
+	}
+	if mode != config.InstallNone && !slices.Contains(modeEngines(mode), eng) {
+		return fmt.Errorf("--install %s does not install %s (use --install %s)", mode, eng, eng)
+	}
+
+	serveCfg := config.ServeConfig{
+		Engine:         eng,
+		Model:          *model,
+		TP:             *tp,
+		Host:           *host,
+		Port:           *port,
+		Daemon:         *daemon,
+		ExtraArgs:      *extraArgs,
+		LogFile:        ctx.LogFile,
+		Backend:        *backend,
+		Quantization:   *quantization,
+		ToolCallParser: *toolCallParser,
+		GPUs:           *gpus,
+		Runtime:        *runtime,
+		Image:          *image,
+		ShmSize:        *shmSize,
+		Resources:      resources,
+	}
+
+	selected := engine.Get(eng)
+	if err := validateServeConfig(selected, serveCfg); err != nil {
+		return err
+	}
+	caps := selected.Capabilities()
+
 	fmt.Fprintln(ctx.Stdout, ui.Banner())
 	fmt.Fprintln(ctx.Stdout, ui.Step("Hermes Pipeline: doctor → install → serve → verify"))
 	fmt.Fprintln(ctx.Stdout, ui.HR())
@@ -62,7 +112,9 @@ func Run(ctx *app.AppContext, args []string) error {
 	fmt.Fprintln(ctx.Stdout)
 	fmt.Fprintln(ctx.Stdout, ui.Step("Phase 2: Install"))
 	fmt.Fprintln(ctx.Stdout, ui.HR())
-	if err := runInstallPhase(ctx, config.InstallMode(*installMode)); err != nil {
+	if *runtime != "" {
+		fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Skipping installation (engine runs in a %s container)", *runtime)))
+	} else if err := runInstallPhase(ctx, mode); err != nil {
 		return err
 	}
 
@@ -70,27 +122,17 @@ func Run(ctx *app.AppContext, args []string) error {
 	fmt.Fprintln(ctx.Stdout, ui.Step("Phase 3: Serve"))
 	fmt.Fprintln(ctx.Stdout, ui.HR())
 
-	serveCfg := config.ServeConfig{
-		Engine:    eng,
-		Model:     *model,
-		TP:        *tp,
-		Host:      *host,
-		Port:      *port,
-		Daemon:    *daemon,
-		ExtraArgs: *extraArgs,
-		LogFile:   ctx.LogFile,
-	}
-
 	if err := runServePhase(ctx, serveCfg); err != nil {
 		return err
 	}
 
-	base := fmt.Sprintf("http://127.0.0.1:%d", *port)
+	base := localEndpoint(serveCfg)
 
 	fmt.Fprintln(ctx.Stdout)
 	fmt.Fprintln(ctx.Stdout, ui.Step("Phase 4: Readiness"))
 	fmt.Fprintln(ctx.Stdout, ui.HR())
-	if err := waitForReadiness(ctx, base, time.Duration(*readinessTimeout)*time.Second); err != nil {
+	probes := selected.ReadinessProbes(serveCfg)
+	if _, err := waitForReadiness(ctx, base, probes, time.Duration(*readinessTimeout)*time.Second); err != nil {
 		return err
 	}
 
@@ -98,7 +140,7 @@ func Run(ctx *app.AppContext, args []string) error {
 		fmt.Fprintln(ctx.Stdout)
 		fmt.Fprintln(ctx.Stdout, ui.Step("Phase 5: Verify"))
 		fmt.Fprintln(ctx.Stdout, ui.HR())
-		result := runVerify(ctx, base, 60*time.Second, true, false)
+		result := runVerify(ctx, base, caps, nil, 60*time.Second, true, false)
 		if result.Status != "ok" {
 			return fmt.Errorf("verification failed: %s", result.Message)
 		}
@@ -106,10 +148,14 @@ func Run(ctx *app.AppContext, args []string) error {
 
 	fmt.Fprintln(ctx.Stdout)
 	fmt.Fprintln(ctx.Stdout, ui.HR())
+	if ctx.DryRun {
+		fmt.Fprintln(ctx.Stdout, ui.Info("Dry run complete: nothing was installed or started"))
+		return nil
+	}
 	fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("Hermes is operational: %s", base)))
 	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Logs: tail -f %s", ctx.LogFile)))
 
-	if !*daemon {
+	if !*daemon && !ctx.DryRun {
 		fmt.Fprintln(ctx.Stdout, ui.Info("Foreground mode: Ctrl+C to stop"))
 		select {}
 	}
@@ -118,34 +164,12 @@ func Run(ctx *app.AppContext, args []string) error {
 }
 
 func runDoctorPhase(ctx *app.AppContext) error {
-	checks := []struct {
-		name  string
-		check func() (bool, string)
-	}{
-		{"nvidia-smi", func() (bool, string) {
-			result := checkNvidiaSMI(ctx, true)
-			return result.Status == StatusOK, result.Message
-		}},
-		{"uv", func() (bool, string) {
-			result := checkUV(ctx, true)
-			return result.Status == StatusOK || result.Status == StatusWarning, result.Message
-		}},
-		{"python", func() (bool, string) {
-			result := checkPython(ctx, true)
-			return result.Status == StatusOK, result.Message
-		}},
-	}
+	results := displayChecks(ctx, newCheckRun(), checksByID("nvidia-smi", "uv", "python"))
 
 	allPassed := true
-	for _, c := range checks {
-		ok, msg := c.check()
-		if ok {
-			fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("%s: %s", c.name, msg)))
-		} else {
-			fmt.Fprintln(ctx.Stdout, ui.Warn(fmt.Sprintf("%s: %s", c.name, msg)))
-			if c.name == "nvidia-smi" {
-				allPassed = false
-			}
+	for _, r := range results {
+		if r.Severity == SeverityRequired && r.Status == StatusFail {
+			allPassed = false
 		}
 	}
 
@@ -171,32 +195,3 @@ func runServePhase(ctx *app.AppContext, cfg config.ServeConfig) error {
 
 	return runServe(ctx, cfg)
 }
-
-func waitForReadiness(ctx *app.AppContext, base string, timeout time.Duration) error {
-	client := &http.Client{Timeout: 5 * time.Second}
-	deadline := time.Now().Add(timeout)
-	checkInterval := 2 * time.Second
-
-	endpoints := []string{"/v1/models", "/health"}
-
-	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Waiting for server at %s (timeout: %s)", base, timeout)))
-
-	for time.Now().Before(deadline) {
-		for _, endpoint := range endpoints {
-			resp, err := client.Get(base + endpoint)
-			if err == nil && resp.StatusCode == http.StatusOK {
-				resp.Body.Close()
-				fmt.Fprintln(ctx.Stdout, ui.Ok("Server is ready"))
-				return nil
-			}
-			if resp != nil {
-				resp.Body.Close()
-			}
-		}
-		time.Sleep(checkInterval)
-		fmt.Fprint(ctx.Stdout, ".")
-	}
-
-	fmt.Fprintln(ctx.Stdout)
-	return fmt.Errorf("timeout waiting for server readiness")
-}
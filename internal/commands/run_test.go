@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+)
+
+// fakeNvidiaSMI answers the XML query from the inventory fixtures and
+// everything else, such as the compute capability query, with nothing.
+func fakeNvidiaSMI(t *testing.T) {
+	t.Helper()
+	fixture, err := filepath.Abs(filepath.Join("..", "gpu", "testdata", "h100-driver550.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeCommand(t, "nvidia-smi", "#!/bin/sh\ncase \"$*\" in *-x*) cat '"+fixture+"' ;; esac\n")
+}
+
+func TestRunInstallsSelectedEngine(t *testing.T) {
+	isolateHome(t)
+	fakeNvidiaSMI(t)
+	fakeCommand(t, "uv", "#!/bin/sh\necho 'uv 0.4.0'\n")
+	home, _ := os.UserHomeDir()
+	env := filepath.Join(home, ".cache", "hermes", "envs", "lmdeploy")
+
+	ctx, out := newTestContextWith(t, app.GlobalFlags{NoColor: true, DryRun: true})
+	if err := Run(ctx, []string{"--engine", "lmdeploy", "--model", "org/model", "--tp", "1"}); err != nil {
+		t.Fatalf("run: %v\n%s", err, out)
+	}
+
+	var installs, launches [][]string
+	for _, step := range ctx.Plan().Steps {
+		switch {
+		case step.Kind == "launch":
+			launches = append(launches, step.Command)
+		case len(step.Command) > 1 && step.Command[0] == "uv":
+			installs = append(installs, step.Command)
+		}
+	}
+	wantInstalls := [][]string{
+		{"uv", "venv", env},
+		{"uv", "pip", "install", "--python", filepath.Join(env, "bin", "python"), "-U", "lmdeploy>=0.6"},
+	}
+	if !slices.EqualFunc(installs, wantInstalls, slices.Equal) {
+		t.Errorf("install steps:\n got %q\nwant %q", installs, wantInstalls)
+	}
+	if len(launches) != 1 || !strings.HasPrefix(launches[0][0], filepath.Join(env, "bin", "lmdeploy")) {
+		t.Errorf("launch steps = %q, want lmdeploy from %s", launches, env)
+	}
+}
+
+func TestRunRejectsInstallModeWithoutEngine(t *testing.T) {
+	isolateHome(t)
+	ctx, _ := newTestContext(t)
+	err := Run(ctx, []string{"--engine", "lmdeploy", "--model", "org/model", "--install", "both"})
+	if err == nil || !strings.Contains(err.Error(), "does not install lmdeploy") {
+		t.Errorf("run --install both --engine lmdeploy returned %v", err)
+	}
+}
@@ -1,35 +1,56 @@
 package commands
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/svngoku/hermes-cli/internal/app"
 	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/container"
 	"github.com/svngoku/hermes-cli/internal/engine"
+	"github.com/svngoku/hermes-cli/internal/execx"
+	"github.com/svngoku/hermes-cli/internal/gpu"
 	"github.com/svngoku/hermes-cli/internal/ui"
 )
 
 func Serve(ctx *app.AppContext, args []string) error {
 	fs := flag.NewFlagSet("serve", flag.ExitOnError)
-	engineName := fs.String("engine", "sglang", "Engine: sglang|vllm")
+	engineName := fs.String("engine", "sglang", "Engine: sglang|vllm|lmdeploy")
 	model := fs.String("model", "", "Model path or HuggingFace repo")
 	tp := fs.Int("tp", 4, "Tensor parallel size")
 	host := fs.String("host", "0.0.0.0", "Bind host")
 	port := fs.Int("port", 30000, "Bind port")
 	daemon := fs.Bool("daemon", false, "Run in daemon mode")
 	extraArgs := fs.String("extra-args", "", "Additional engine arguments")
+	backend := fs.String("backend", "", "lmdeploy backend: turbomind|pytorch")
+	quantization := fs.String("quantization", "", "Quantization method (see 'hermes engines')")
+	toolCallParser := fs.String("tool-call-parser", "", "Tool-call parser (see 'hermes engines')")
+	readinessTimeout := fs.Int("readiness-timeout", 300, "Readiness probe timeout in seconds for foreground mode (0 disables)")
+	gpus := fs.String("gpus", "", "Comma-separated GPU indexes to use, or auto for the best-connected idle GPUs (default: all)")
+	runtime := fs.String("runtime", "", "Run the engine's image with docker|podman instead of its managed environment")
+	image := fs.String("image", "", "Container image (default: the engine's official image)")
+	shmSize := fs.String("shm-size", "16g", "Container shared memory size")
+	skipFlagCheck := fs.Bool("skip-flag-check", false, "Launch without validating flags against the installed engine")
+	var env stringList
+	fs.Var(&env, "env", "Container environment variable as KEY=VALUE (repeatable)")
+	var resources config.Resources
+	addResourceFlags(fs, &resources)
 	fs.Usage = func() {
 		fmt.Fprintln(ctx.Stdout, "Usage: hermes serve [flags]")
 		fmt.Fprintln(ctx.Stdout)
 		fmt.Fprintln(ctx.Stdout, "Start inference server")
 		fmt.Fprintln(ctx.Stdout)
 		fs.PrintDefaults()
+		printEngineOptions(ctx)
 	}
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -45,25 +66,89 @@ func Serve(ctx *app.AppContext, args []string) error {
 		eng = config.EngineSGLang
 	case "vllm":
 		eng = config.EngineVLLM
+	case "lmdeploy":
+		eng = config.EngineLMDeploy
 	default:
-		return fmt.Errorf("invalid engine: %s (use sglang or vllm)", *engineName)
+		return fmt.Errorf("invalid engine: %s (use sglang, vllm or lmdeploy)", *engineName)
+	}
+
+	if *backend != "" && *backend != "turbomind" && *backend != "pytorch" {
+		return fmt.Errorf("invalid backend: %s (use turbomind or pytorch)", *backend)
 	}
 
 	cfg := config.ServeConfig{
-		Engine:    eng,
-		Model:     *model,
-		TP:        *tp,
-		Host:      *host,
-		Port:      *port,
-		Daemon:    *daemon,
-		ExtraArgs: *extraArgs,
-		LogFile:   ctx.LogFile,
+		Engine:           eng,
+		Model:            *model,
+		TP:               *tp,
+		Host:             *host,
+		Port:             *port,
+		Daemon:           *daemon,
+		ExtraArgs:        *extraArgs,
+		LogFile:          ctx.LogFile,
+		Backend:          *backend,
+		Quantization:     *quantization,
+		ToolCallParser:   *toolCallParser,
+		ReadinessTimeout: *readinessTimeout,
+		GPUs:             *gpus,
+		Runtime:          *runtime,
+		Image:            *image,
+		ShmSize:          *shmSize,
+		Env:              env,
+		Resources:        resources,
+		SkipFlagCheck:    *skipFlagCheck,
 	}
 
 	return runServe(ctx, cfg)
 }
 
 func runServe(ctx *app.AppContext, cfg config.ServeConfig) error {
+	var eng engine.Engine
+	var rt container.Runtime
+	if cfg.Runtime != "" {
+		var err error
+		if rt, err = container.Parse(cfg.Runtime); err != nil {
+			return err
+		}
+		if !rt.Available(ctx.Ctx) {
+			return fmt.Errorf("%s not found in PATH", rt)
+		}
+		if cfg.Image == "" {
+			cfg.Image = container.DefaultImage(cfg.Engine)
+		}
+		eng = engine.WithEnv(cfg.Engine, "")
+	} else {
+		state, _ := loadState()
+		eng = stateEngine(state, cfg.Engine)
+	}
+	if eng == nil {
+		return fmt.Errorf("unknown engine: %s", cfg.Engine)
+	}
+	if err := validateServeConfig(eng, cfg); err != nil {
+		return err
+	}
+	if rt == "" {
+		if err := checkResourcePrivileges(cfg.Resources); err != nil {
+			return err
+		}
+	} else if cfg.Resources.Nice != 0 || cfg.Resources.IONice != "" {
+		return fmt.Errorf("--nice and --ionice are not supported with --runtime")
+	}
+	if rt == "" && !eng.Env().Exists() && !plansEnv(ctx, eng.Env().Path) {
+		return fmt.Errorf("%s environment not found at %s (run: hermes install --install %s)",
+			cfg.Engine, eng.Env().Path, cfg.Engine)
+	}
+
+	gpus, err := resolveGPUs(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	cfg.GPUs = gpus
+
+	id := instanceID(cfg)
+	if existing, _ := findInstance(id); existing != nil {
+		return fmt.Errorf("%s is already running (stop it with: hermes stop %s)", id, id)
+	}
+
 	fmt.Fprintln(ctx.Stdout, ui.Banner())
 	fmt.Fprintln(ctx.Stdout, ui.Step(fmt.Sprintf("Starting %s server...", cfg.Engine)))
 	fmt.Fprintln(ctx.Stdout, ui.HR())
@@ -73,24 +158,72 @@ func runServe(ctx *app.AppContext, cfg config.ServeConfig) error {
 	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("TP:     %d", cfg.TP)))
 	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Host:   %s", cfg.Host)))
 	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Port:   %d", cfg.Port)))
+	if cfg.GPUs != "" {
+		fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("GPUs:   %s", cfg.GPUs)))
+	}
+	if cfg.Quantization != "" {
+		fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Quant:  %s", cfg.Quantization)))
+	}
 	if cfg.ExtraArgs != "" {
 		fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Extra:  %s", cfg.ExtraArgs)))
 	}
+	if rt != "" {
+		fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Image:  %s (%s)", cfg.Image, rt)))
+	} else {
+		fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Env:    %s", eng.Env().Path)))
+	}
+	res, err := resolveResources(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	cfg.Resources = res
+	if res.CPUs != "" || res.NUMA != "" {
+		fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("CPUs:   %s (NUMA %s)", orDash(res.CPUs), orDash(res.NUMA))))
+	}
 	fmt.Fprintln(ctx.Stdout, ui.HR())
 
-	eng := engine.Get(cfg.Engine)
-	if eng == nil {
-		return fmt.Errorf("unknown engine: %s", cfg.Engine)
+	inst := Instance{
+		ID:      id,
+		Engine:  string(cfg.Engine),
+		Model:   cfg.Model,
+		Host:    cfg.Host,
+		Port:    cfg.Port,
+		Daemon:  cfg.Daemon,
+		LogFile: absPath(cfg.LogFile),
 	}
 
-	cmdName, cmdArgs := eng.ServeCommand(cfg)
-
-	ctx.Logger.Debug("serve command", "cmd", cmdName, "args", cmdArgs)
+	var cmd *exec.Cmd
+	if rt != "" {
+		inst.Runtime = string(rt)
+		inst.Container = containerName(id)
+		// A container left behind by a crashed daemon would hold the name.
+		rt.Remove(ctx.Ctx, inst.Container)
+		cmd = containerCommand(ctx, rt, eng, cfg, inst.Container)
+	} else {
+		inst.EnvPath = eng.Env().Path
+		cmdName, cmdArgs := eng.ServeCommand(cfg)
+		if !cfg.SkipFlagCheck {
+			if cmdArgs, err = checkServeFlags(ctx, eng, cmdArgs); err != nil {
+				return err
+			}
+		}
+		if cmdName, cmdArgs, err = resourceCommand(ctx, cfg.Resources, cmdName, cmdArgs); err != nil {
+			return err
+		}
+		ctx.Logger.Debug("serve command", "cmd", cmdName, "args", cmdArgs)
+		cmd = exec.CommandContext(ctx.Ctx, cmdName, cmdArgs...)
+		cmd.Env = append(eng.Env().Environ(), gpu.DeviceOrderEnv)
+		if cfg.GPUs != "" {
+			cmd.Env = append(cmd.Env, "CUDA_VISIBLE_DEVICES="+cfg.GPUs)
+		}
+	}
 
-	cmd := exec.CommandContext(ctx.Ctx, cmdName, cmdArgs...)
+	if ctx.DryRun {
+		planServe(ctx, cmd, cfg)
+		return nil
+	}
 
 	var logFile *os.File
-	var err error
 	if cfg.LogFile != "" {
 		logFile, err = os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 		if err != nil {
@@ -99,14 +232,174 @@ func runServe(ctx *app.AppContext, cfg config.ServeConfig) error {
 		defer logFile.Close()
 	}
 
+	if cfg.Daemon && rt != "" {
+		return runContainerDaemon(ctx, cmd, cfg, inst)
+	}
 	if cfg.Daemon {
-		return runDaemon(ctx, cmd, logFile, cfg)
+		return runDaemon(ctx, cmd, logFile, cfg, inst)
+	}
+
+	return runForeground(ctx, cmd, logFile, cfg, inst)
+}
+
+// plansEnv reports whether the dry-run plan already creates the environment
+// at path, as run --dry-run does in its install phase.
+func plansEnv(ctx *app.AppContext, path string) bool {
+	plan := ctx.Plan()
+	if plan == nil {
+		return false
+	}
+	for _, step := range plan.Steps {
+		if len(step.Command) >= 3 && step.Command[0] == "uv" && step.Command[1] == "venv" && step.Command[2] == path {
+			return true
+		}
+	}
+	return false
+}
+
+// containerCommand builds the runtime invocation for the engine's image. The
+// server binds every interface inside the container; the host side of the
+// port mapping honours cfg.Host.
+func containerCommand(ctx *app.AppContext, rt container.Runtime, eng engine.Engine, cfg config.ServeConfig, name string) *exec.Cmd {
+	inner := cfg
+	inner.Host = "0.0.0.0"
+	if abs, err := filepath.Abs(cfg.Model); err == nil && fileExists(cfg.Model) {
+		inner.Model = abs
+	}
+	entrypoint, args := eng.ServeCommand(inner)
+	env, mounts := container.ServeEnv(inner)
+
+	runArgs := rt.RunArgs(container.Spec{
+		Name:       name,
+		Image:      cfg.Image,
+		Entrypoint: entrypoint,
+		Args:       args,
+		Host:       cfg.Host,
+		Port:       cfg.Port,
+		GPUs:       cfg.GPUs,
+		ShmSize:    cfg.ShmSize,
+		Env:        env,
+		Mounts:     mounts,
+		Detach:     cfg.Daemon,
+		Ulimits:    containerUlimits(cfg.Resources),
+		CPUSet:     cfg.Resources.CPUs,
+		CPUSetMems: cfg.Resources.NUMA,
+	})
+	ctx.Logger.Debug("serve command", "cmd", string(rt), "args", runArgs)
+	return exec.CommandContext(ctx.Ctx, string(rt), runArgs...)
+}
+
+// checkServeFlags validates args against the installed engine's flag
+// catalog. Without a catalog the command is launched unchecked.
+func checkServeFlags(ctx *app.AppContext, eng engine.Engine, args []string) ([]string, error) {
+	catalog, err := engine.LoadFlagCatalog(ctx.Ctx, eng)
+	if err != nil {
+		fmt.Fprintln(ctx.Stdout, ui.Warn(fmt.Sprintf("Flags not checked: %s", err)))
+		return args, nil
+	}
+	checked, warnings, err := catalog.CheckServeCommand(eng, args)
+	for _, w := range warnings {
+		fmt.Fprintln(ctx.Stdout, ui.Warn(w))
 	}
+	if err != nil {
+		return nil, fmt.Errorf("%w (pass --skip-flag-check to launch anyway)", err)
+	}
+	fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("Flags checked against %s %s", catalog.Engine, catalog.Version)))
+	return checked, nil
+}
+
+// planServe adds the server launch, and the readiness probes a foreground
+// server would run, to the dry-run plan. Only environment variables hermes
+// adds are listed.
+func planServe(ctx *app.AppContext, cmd *exec.Cmd, cfg config.ServeConfig) {
+	inherited := make(map[string]bool)
+	for _, kv := range os.Environ() {
+		inherited[kv] = true
+	}
+	var env []string
+	for _, kv := range cmd.Env {
+		if !inherited[kv] {
+			env = append(env, kv)
+		}
+	}
+
+	step := execx.Step{Kind: "launch", Command: cmd.Args, Env: env, Background: cfg.Daemon, LogFile: cfg.LogFile,
+		Note: fmt.Sprintf("%s server on port %d", cfg.Engine, cfg.Port)}
+	if cfg.Runtime != "" && cfg.Daemon {
+		// docker run --detach returns once the container is up.
+		step.Kind, step.Background = "exec", false
+	}
+	ctx.Plan().Add(step)
+	fmt.Fprintln(ctx.Stdout, ui.Info("Dry run: server not started"))
+
+	if !cfg.Daemon && cfg.ReadinessTimeout > 0 {
+		planProbes(ctx, "readiness", localEndpoint(cfg), engine.Get(cfg.Engine).ReadinessProbes(cfg))
+	}
+}
+
+func runContainerDaemon(ctx *app.AppContext, cmd *exec.Cmd, cfg config.ServeConfig, inst Instance) error {
+	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Starting container %s...", inst.Container)))
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if _, err := cmd.Output(); err != nil {
+		return fmt.Errorf("failed to start container: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	inst.StartedAt = time.Now()
+	if err := registerInstance(inst); err != nil {
+		ctx.Logger.Warn("failed to register instance", "error", err)
+	}
+
+	fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("Container started (%s)", inst.Container)))
+	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Endpoint: http://%s:%d", cfg.Host, cfg.Port)))
+	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Logs: hermes logs -f %s", inst.ID)))
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func absPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
 
-	return runForeground(ctx, cmd, logFile, cfg)
+// localEndpoint is the base URL hermes itself uses to reach a server bound
+// to cfg.Host, mapping wildcard binds to loopback.
+func localEndpoint(cfg config.ServeConfig) string {
+	host := cfg.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return fmt.Sprintf("http://%s:%d", host, cfg.Port)
+}
+
+// validateServeConfig rejects options the selected engine cannot honour
+// before anything is launched.
+func validateServeConfig(eng engine.Engine, cfg config.ServeConfig) error {
+	caps := eng.Capabilities()
+	if cfg.Quantization != "" && !caps.SupportsQuantization(cfg.Quantization) {
+		return fmt.Errorf("%s does not support quantization %q (supported: %s)",
+			eng.Name(), cfg.Quantization, strings.Join(caps.Quantization, ", "))
+	}
+	if cfg.ToolCallParser != "" && !caps.SupportsToolCallParser(cfg.ToolCallParser) {
+		return fmt.Errorf("%s does not support tool-call parser %q (supported: %s)",
+			eng.Name(), cfg.ToolCallParser, strings.Join(caps.ToolCallParsers, ", "))
+	}
+	if cfg.Backend != "" && cfg.Engine != config.EngineLMDeploy {
+		return fmt.Errorf("--backend is only supported by lmdeploy")
+	}
+	return nil
 }
 
-func runDaemon(ctx *app.AppContext, cmd *exec.Cmd, logFile *os.File, cfg config.ServeConfig) error {
+func runDaemon(ctx *app.AppContext, cmd *exec.Cmd, logFile *os.File, cfg config.ServeConfig, inst Instance) error {
 	if logFile != nil {
 		cmd.Stdout = logFile
 		cmd.Stderr = logFile
@@ -120,6 +413,12 @@ func runDaemon(ctx *app.AppContext, cmd *exec.Cmd, logFile *os.File, cfg config.
 		return fmt.Errorf("failed to start daemon: %w", err)
 	}
 
+	inst.PID = cmd.Process.Pid
+	inst.StartedAt = time.Now()
+	if err := registerInstance(inst); err != nil {
+		ctx.Logger.Warn("failed to register instance", "error", err)
+	}
+
 	fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("Daemon started (pid=%d)", cmd.Process.Pid)))
 	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Endpoint: http://%s:%d", cfg.Host, cfg.Port)))
 	if cfg.LogFile != "" {
@@ -129,16 +428,29 @@ func runDaemon(ctx *app.AppContext, cmd *exec.Cmd, logFile *os.File, cfg config.
 	return nil
 }
 
-func runForeground(ctx *app.AppContext, cmd *exec.Cmd, logFile *os.File, cfg config.ServeConfig) error {
-	var writers []io.Writer
-	writers = append(writers, ctx.Stdout)
-	if logFile != nil {
-		writers = append(writers, logFile)
-	}
-	multiWriter := io.MultiWriter(writers...)
+const (
+	serveTailLines  = 200
+	serveErrorLines = 20
+)
+
+func runForeground(ctx *app.AppContext, cmd *exec.Cmd, logFile *os.File, cfg config.ServeConfig, inst Instance) error {
+	// Server output is prefixed so it stays distinct from readiness reports
+	// printed alongside it; the log file gets timestamped, tagged lines.
+	prefix := fmt.Sprintf("%s │ ", cfg.Engine)
+	sink := execx.NewLineSink(execx.StreamOptions{
+		Output:    ctx.Stdout,
+		Prefix:    prefix,
+		TailLines: serveTailLines,
+		OnLine: func(l execx.Line) {
+			if logFile != nil {
+				io.WriteString(logFile, execx.FormatLine(l, "", true))
+			}
+		},
+	})
+	defer sink.Close()
 
-	cmd.Stdout = multiWriter
-	cmd.Stderr = multiWriter
+	cmd.Stdout = sink.Stdout()
+	cmd.Stderr = sink.Stderr()
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -147,6 +459,13 @@ func runForeground(ctx *app.AppContext, cmd *exec.Cmd, logFile *os.File, cfg con
 		return fmt.Errorf("failed to start server: %w", err)
 	}
 
+	inst.PID = cmd.Process.Pid
+	inst.StartedAt = time.Now()
+	if err := registerInstance(inst); err != nil {
+		ctx.Logger.Warn("failed to register instance", "error", err)
+	}
+	defer unregisterInstance(inst.ID)
+
 	fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("Server started (pid=%d)", cmd.Process.Pid)))
 	fmt.Fprintln(ctx.Stdout, ui.Info(fmt.Sprintf("Endpoint: http://%s:%d", cfg.Host, cfg.Port)))
 	fmt.Fprintln(ctx.Stdout, ui.Info("Ctrl+C to stop"))
@@ -157,6 +476,20 @@ func runForeground(ctx *app.AppContext, cmd *exec.Cmd, logFile *os.File, cfg con
 		done <- cmd.Wait()
 	}()
 
+	if cfg.ReadinessTimeout > 0 {
+		probeCtx := *ctx
+		var cancel context.CancelFunc
+		probeCtx.Ctx, cancel = context.WithCancel(ctx.Ctx)
+		defer cancel()
+		probes := engine.Get(cfg.Engine).ReadinessProbes(cfg)
+		go func() {
+			timeout := time.Duration(cfg.ReadinessTimeout) * time.Second
+			if _, err := waitForReadiness(&probeCtx, localEndpoint(cfg), probes, timeout); err != nil && probeCtx.Ctx.Err() == nil {
+				ctx.Logger.Warn("server did not become ready", "error", err)
+			}
+		}()
+	}
+
 	select {
 	case sig := <-sigChan:
 		ctx.Logger.Info("received signal, shutting down", "signal", sig)
@@ -166,10 +499,34 @@ func runForeground(ctx *app.AppContext, cmd *exec.Cmd, logFile *os.File, cfg con
 		fmt.Fprintln(ctx.Stdout, ui.Ok("Server stopped"))
 		return nil
 	case err := <-done:
+		sink.Close()
 		if err != nil {
+			printServeTail(ctx, sink.Tail())
 			return fmt.Errorf("server exited with error: %w", err)
 		}
 		fmt.Fprintln(ctx.Stdout, ui.Ok("Server exited"))
 		return nil
 	}
 }
+
+// printServeTail repeats the server's last stderr lines after a crash, when
+// the cause has usually scrolled past amid normal output.
+func printServeTail(ctx *app.AppContext, tail *execx.Tail) {
+	var lines []string
+	for _, l := range tail.Lines() {
+		if l.Stream == execx.Stderr {
+			lines = append(lines, l.Text)
+		}
+	}
+	if len(lines) > serveErrorLines {
+		lines = lines[len(lines)-serveErrorLines:]
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintln(ctx.Stdout, ui.HR())
+	fmt.Fprintln(ctx.Stdout, ui.Fail("Last server output:"))
+	for _, line := range lines {
+		fmt.Fprintln(ctx.Stdout, "    "+line)
+	}
+}
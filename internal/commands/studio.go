@@ -29,7 +29,7 @@ func Studio(ctx *app.AppContext, args []string) error {
 	fmt.Fprintln(ctx.Stdout, ui.Step("vLLM Studio"))
 	fmt.Fprintln(ctx.Stdout, ui.HR())
 
-	result := execx.Run(ctx.Ctx, "python", "-c", "import vllm_studio; print(vllm_studio.__version__)")
+	result := ctx.Runner.Run(execx.ReadOnly(ctx.Ctx), "python", "-c", "import vllm_studio; print(vllm_studio.__version__)")
 	installed := result.ExitCode == 0
 
 	if *check {
@@ -70,11 +70,17 @@ func Studio(ctx *app.AppContext, args []string) error {
 
 	fmt.Fprintln(ctx.Stdout, ui.HR())
 
-	return execx.RunWithStreaming(
-		ctx.Ctx,
-		ctx.Stdout,
-		ctx.Stderr,
-		"vllm-studio",
-		"--port", fmt.Sprintf("%d", *studioPort),
-	)
+	stream := execx.Stream(ctx.Ctx, execx.StreamOptions{
+		Output:    ctx.Stdout,
+		Prefix:    "studio │ ",
+		TailLines: 20,
+	}, "vllm-studio", "--port", fmt.Sprintf("%d", *studioPort))
+	if stream.Err != nil && ctx.Ctx.Err() == nil {
+		fmt.Fprintln(ctx.Stdout, ui.HR())
+		fmt.Fprintln(ctx.Stdout, ui.Fail("vllm-studio exited; last output:"))
+		for _, l := range stream.Tail.Lines() {
+			fmt.Fprintln(ctx.Stdout, "    "+l.Text)
+		}
+	}
+	return stream.Err
 }
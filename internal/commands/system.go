@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+)
+
+// hostIPCNamespace is the inode of the initial IPC namespace; a process
+// that shares the host's IPC namespace sees this one.
+const hostIPCNamespace = "ipc:[4026531839]"
+
+// Thresholds for the system checks. Tensor-parallel ranks exchange data
+// through NCCL, whose intra-node transport allocates its buffers in /dev/shm
+// and whose InfiniBand transport pins host memory.
+const (
+	shmPerRank     = 1 * gib
+	minShmForTP    = 512 << 20
+	minMemlockTP   = 1 * gib
+	minNoFile      = 8192
+	minNoFilePerTP = 16384
+)
+
+// inContainer reports whether hermes runs inside a Docker, Podman or
+// Kubernetes container.
+func inContainer() bool {
+	for _, marker := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(marker); err == nil {
+			return true
+		}
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" || os.Getenv("container") != "" {
+		return true
+	}
+	data, _ := os.ReadFile("/proc/1/cgroup")
+	for _, runtime := range []string{"docker", "kubepods", "containerd", "libpod"} {
+		if strings.Contains(string(data), runtime) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkShm checks the size of /dev/shm against the requested TP size.
+func checkShm(ctx *app.AppContext, run *checkRun) CheckResult {
+	var check CheckResult
+
+	var st unix.Statfs_t
+	if err := unix.Statfs("/dev/shm", &st); err != nil {
+		check.Status = StatusWarning
+		check.Message = "/dev/shm is not mounted"
+		check.Details = err.Error()
+		return check
+	}
+	size := int64(st.Blocks) * int64(st.Bsize)
+	free := int64(st.Bavail) * int64(st.Bsize)
+
+	var details []string
+	container := inContainer()
+	if container {
+		switch ns, err := os.Readlink("/proc/self/ns/ipc"); {
+		case err != nil:
+		case ns == hostIPCNamespace:
+			details = append(details, "running in a container that shares the host's IPC namespace")
+		default:
+			details = append(details, "running in a container with a private IPC namespace; /dev/shm is the container's own")
+		}
+	}
+	check.Status, check.Message, check.Remediation = shmVerdict(run.tp, size, free, container)
+	check.Details = strings.Join(details, "\n")
+	return check
+}
+
+// shmVerdict grades a /dev/shm of size bytes, free of them unused, for tp
+// ranks. Below minShmForTP NCCL fails to start; below the recommended size
+// it may run out under load.
+func shmVerdict(tp int, size, free int64, container bool) (status CheckStatus, message, remediation string) {
+	want := shmWant(tp)
+	switch {
+	case tp > 1 && size < minShmForTP:
+		status = StatusFail
+		message = fmt.Sprintf("/dev/shm is %s; TP=%d needs at least %s for NCCL (%s recommended)",
+			formatBytes(size), tp, formatBytes(minShmForTP), formatBytes(want))
+	case size < want:
+		status = StatusWarning
+		message = fmt.Sprintf("/dev/shm is %s; %s is recommended for TP=%d", formatBytes(size), formatBytes(want), max(tp, 1))
+	case free < want/2:
+		status = StatusWarning
+		message = fmt.Sprintf("/dev/shm has %s of %s free", formatBytes(free), formatBytes(size))
+		remediation = "Remove stale files from /dev/shm left by crashed servers"
+	default:
+		status = StatusOK
+		message = fmt.Sprintf("/dev/shm: %s (%s free)", formatBytes(size), formatBytes(free))
+	}
+	if status != StatusOK && remediation == "" {
+		if container {
+			remediation = fmt.Sprintf("Restart the container with --ipc=host or --shm-size=%s", strings.ToLower(shmSizeArg(want)))
+		} else {
+			remediation = "Enlarge it with: " + shmRemount(tp)
+		}
+	}
+	return status, message, remediation
+}
+
+// shmWant is the /dev/shm size recommended for tp ranks.
+func shmWant(tp int) int64 {
+	return int64(max(tp, 1)) * shmPerRank
+}
+
+// shmSizeArg renders size in whole GiB, rounded up, as mount's size= option
+// takes it.
+func shmSizeArg(size int64) string {
+	return fmt.Sprintf("%dG", (size+gib-1)/gib)
+}
+
+// shmRemount is the command that enlarges a host's /dev/shm for tp ranks.
+func shmRemount(tp int) string {
+	return fmt.Sprintf("sudo mount -o remount,size=%s /dev/shm", shmSizeArg(shmWant(tp)))
+}
+
+// checkMemlock checks the locked-memory limit, which bounds the host memory
+// NCCL and RDMA can pin.
+func checkMemlock(ctx *app.AppContext, run *checkRun) CheckResult {
+	var check CheckResult
+
+	var lim unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_MEMLOCK, &lim); err != nil {
+		check.Status = StatusWarning
+		check.Message = "Could not read the memlock limit"
+		check.Details = err.Error()
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Message = fmt.Sprintf("memlock: %s (hard %s)", formatLimitBytes(lim.Cur), formatLimitBytes(lim.Max))
+	if run.tp <= 1 || lim.Cur == unix.RLIM_INFINITY {
+		return check
+	}
+	if lim.Cur < minMemlockTP {
+		check.Status = StatusWarning
+		check.Message = fmt.Sprintf("memlock is %s; TP=%d over InfiniBand or with pinned buffers needs it unlimited", formatLimitBytes(lim.Cur), run.tp)
+	} else {
+		check.Details = "not unlimited; NCCL over InfiniBand may still fail to register memory"
+	}
+	check.Remediation = memlockRemediation(lim)
+	return check
+}
+
+func memlockRemediation(lim unix.Rlimit) string {
+	switch {
+	case inContainer():
+		return "Restart the container with --ulimit memlock=-1"
+	case lim.Max == unix.RLIM_INFINITY:
+		return "Serve with --memlock unlimited, or add to /etc/security/limits.conf: * soft memlock unlimited"
+	default:
+		return "Add to /etc/security/limits.conf: * soft memlock unlimited and * hard memlock unlimited, then log in again"
+	}
+}
+
+// checkNoFile checks the open-file limit; every rank holds model shards,
+// sockets and CUDA IPC handles open, and the API server one socket per
+// client.
+func checkNoFile(ctx *app.AppContext, run *checkRun) CheckResult {
+	var check CheckResult
+
+	var lim unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_NOFILE, &lim); err != nil {
+		check.Status = StatusWarning
+		check.Message = "Could not read the open-file limit"
+		check.Details = err.Error()
+		return check
+	}
+
+	want := noFileWant(run.tp)
+	if lim.Cur >= want {
+		check.Status = StatusOK
+		check.Message = fmt.Sprintf("nofile: %s (hard %s)", formatLimit(lim.Cur), formatLimit(lim.Max))
+		return check
+	}
+
+	check.Status = StatusWarning
+	check.Message = fmt.Sprintf("nofile is %s; %d is recommended for TP=%d", formatLimit(lim.Cur), want, max(run.tp, 1))
+	switch {
+	case inContainer():
+		check.Remediation = fmt.Sprintf("Restart the container with --ulimit nofile=%d:%d", want, want)
+	case lim.Max >= want:
+		check.Remediation = fmt.Sprintf("Serve with --nofile %d, or run ulimit -n %d first", want, want)
+	default:
+		check.Remediation = fmt.Sprintf("Add to /etc/security/limits.conf: * soft nofile %d and * hard nofile %d, then log in again", want, want)
+	}
+	return check
+}
+
+func noFileWant(tp int) uint64 {
+	if tp > 1 {
+		return uint64(minNoFilePerTP * tp)
+	}
+	return minNoFile
+}
+
+// ncclHazards are NCCL settings that are sometimes left over from debugging
+// and quietly slow down tensor parallelism.
+var ncclHazards = map[string]string{
+	"NCCL_P2P_DISABLE": "disables direct GPU-to-GPU transfers over NVLink and PCIe",
+	"NCCL_SHM_DISABLE": "disables the shared-memory transport between ranks",
+}
+
+// checkNCCL reports the NCCL environment the engine will inherit, and the
+// settings that hurt tensor parallelism.
+func checkNCCL(ctx *app.AppContext, run *checkRun) CheckResult {
+	var check CheckResult
+
+	var set []string
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "NCCL_") {
+			set = append(set, kv)
+		}
+	}
+	sort.Strings(set)
+
+	var hazards []string
+	if run.tp > 1 {
+		for _, kv := range set {
+			name, value, _ := strings.Cut(kv, "=")
+			if why, ok := ncclHazards[name]; ok && value == "1" {
+				hazards = append(hazards, fmt.Sprintf("%s=1 %s", name, why))
+			}
+		}
+	}
+
+	switch {
+	case len(hazards) > 0:
+		check.Status = StatusWarning
+		check.Message = fmt.Sprintf("NCCL environment may slow down TP=%d", run.tp)
+		check.Details = strings.Join(hazards, "\n")
+		check.Remediation = "Unset these unless a known hardware problem requires them"
+	case len(set) > 0:
+		check.Status = StatusOK
+		check.Message = fmt.Sprintf("NCCL: %d setting(s) in the environment", len(set))
+		check.Details = strings.Join(set, "\n")
+	default:
+		check.Status = StatusOK
+		check.Message = "NCCL: defaults (no NCCL_* variables set)"
+	}
+	return check
+}
+
+func formatLimitBytes(v uint64) string {
+	if v == unix.RLIM_INFINITY {
+		return "unlimited"
+	}
+	return formatBytes(int64(v))
+}
@@ -0,0 +1,78 @@
+package commands
+
+import "testing"
+
+func TestShmVerdict(t *testing.T) {
+	const mib = 1 << 20
+	tests := []struct {
+		name        string
+		tp          int
+		size, free  int64
+		container   bool
+		status      CheckStatus
+		message     string
+		remediation string
+	}{
+		{
+			name: "below the NCCL minimum", tp: 8, size: 64 * mib, free: 64 * mib,
+			status:      StatusFail,
+			message:     "/dev/shm is 64.0 MiB; TP=8 needs at least 512.0 MiB for NCCL (8.0 GiB recommended)",
+			remediation: "Enlarge it with: sudo mount -o remount,size=8G /dev/shm",
+		},
+		{
+			name: "below the recommendation", tp: 4, size: 2 * gib, free: 2 * gib,
+			status:      StatusWarning,
+			message:     "/dev/shm is 2.0 GiB; 4.0 GiB is recommended for TP=4",
+			remediation: "Enlarge it with: sudo mount -o remount,size=4G /dev/shm",
+		},
+		{
+			name: "below the recommendation in a container", tp: 2, size: 64 * mib, free: 64 * mib, container: true,
+			status:      StatusFail,
+			message:     "/dev/shm is 64.0 MiB; TP=2 needs at least 512.0 MiB for NCCL (2.0 GiB recommended)",
+			remediation: "Restart the container with --ipc=host or --shm-size=2g",
+		},
+		{
+			name: "small but enough without TP", tp: 1, size: 64 * mib, free: 64 * mib,
+			status:      StatusWarning,
+			message:     "/dev/shm is 64.0 MiB; 1.0 GiB is recommended for TP=1",
+			remediation: "Enlarge it with: sudo mount -o remount,size=1G /dev/shm",
+		},
+		{
+			name: "mostly used", tp: 4, size: 16 * gib, free: 1 * gib,
+			status:      StatusWarning,
+			message:     "/dev/shm has 1.0 GiB of 16.0 GiB free",
+			remediation: "Remove stale files from /dev/shm left by crashed servers",
+		},
+		{
+			name: "large enough", tp: 8, size: 32 * gib, free: 30 * gib,
+			status:  StatusOK,
+			message: "/dev/shm: 32.0 GiB (30.0 GiB free)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, message, remediation := shmVerdict(tt.tp, tt.size, tt.free, tt.container)
+			if status != tt.status || message != tt.message || remediation != tt.remediation {
+				t.Errorf("shmVerdict(%d, %d, %d, %v) =\n  %v, %q, %q\nwant\n  %v, %q, %q",
+					tt.tp, tt.size, tt.free, tt.container, status, message, remediation, tt.status, tt.message, tt.remediation)
+			}
+		})
+	}
+}
+
+func TestShmSizeArg(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{gib, "1G"},
+		{8 * gib, "8G"},
+		{gib + 1, "2G"},
+		{512 << 20, "1G"},
+	}
+	for _, tt := range tests {
+		if got := shmSizeArg(tt.size); got != tt.want {
+			t.Errorf("shmSizeArg(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/gpu"
+)
+
+// TPGroup is the GPU set recommended for a tensor-parallel size.
+type TPGroup struct {
+	TP          int    `json:"tp"`
+	GPUs        []int  `json:"gpus"`
+	WeakestLink string `json:"weakest_link,omitempty"`
+	NUMA        string `json:"numa_affinity,omitempty"`
+	// Busy is set when idle GPUs were too few and busy ones were included.
+	Busy bool `json:"busy,omitempty"`
+}
+
+// recommendGPUs picks the best TP group, preferring GPUs no process holds
+// memory on. inv may be nil, in which case every GPU is a candidate.
+func recommendGPUs(topo *gpu.Topology, inv *gpu.Inventory, tp int) (*TPGroup, error) {
+	var all, idle []int
+	for _, g := range topo.GPUs {
+		all = append(all, g.Index)
+	}
+	if inv != nil {
+		for _, g := range inv.GPUs {
+			if len(g.Processes) == 0 {
+				idle = append(idle, g.Index)
+			}
+		}
+	}
+	group := &TPGroup{TP: tp}
+	candidates := idle
+	if inv == nil || len(idle) < tp {
+		candidates = all
+		group.Busy = inv != nil
+	}
+	best, err := topo.BestGroup(tp, candidates)
+	if err != nil {
+		return nil, err
+	}
+	group.GPUs = best
+	group.WeakestLink = topo.WeakestLink(best)
+	_, group.NUMA = topo.Affinity(best)
+	return group, nil
+}
+
+func joinInts(ints []int) string {
+	s := make([]string, len(ints))
+	for i, n := range ints {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
+
+// linkDescription names a link type for people.
+func linkDescription(link string) string {
+	switch {
+	case strings.HasPrefix(link, "NV"):
+		return fmt.Sprintf("NVLink x%s", strings.TrimPrefix(link, "NV"))
+	case link == "PIX" || link == "PXB":
+		return link + ": PCIe switch"
+	case link == "PHB":
+		return "PHB: PCIe host bridge"
+	case link == "NODE":
+		return "NODE: PCIe across host bridges of one NUMA node"
+	case link == "SYS":
+		return "SYS: across CPU sockets"
+	default:
+		return link
+	}
+}
+
+// checkTopology reports NVLink islands and the NUMA node of each GPU, and
+// with --tp above one the GPUs a server of that size should use.
+func checkTopology(ctx *app.AppContext, run *checkRun) CheckResult {
+	var check CheckResult
+
+	topo, err := gpu.LoadTopology(ctx.Ctx)
+	if err != nil {
+		check.Status = StatusWarning
+		check.Message = "Could not read the GPU topology"
+		check.Details = err.Error()
+		return check
+	}
+	run.setTopology(topo)
+
+	var details []string
+	islands := topo.NVLinkIslands()
+	for _, island := range islands {
+		details = append(details, fmt.Sprintf("NVLink island: GPU %s (%s)", joinInts(island), linkDescription(topo.WeakestLink(island))))
+	}
+	byNode := make(map[string][]int)
+	var nodes []string
+	for _, g := range topo.GPUs {
+		if _, ok := byNode[g.NUMA]; !ok {
+			nodes = append(nodes, g.NUMA)
+		}
+		byNode[g.NUMA] = append(byNode[g.NUMA], g.Index)
+	}
+	for _, node := range nodes {
+		if node == "" {
+			continue
+		}
+		cpus, _ := topo.Affinity(byNode[node])
+		details = append(details, fmt.Sprintf("NUMA %s: GPU %s (CPUs %s)", node, joinInts(byNode[node]), orDash(cpus)))
+	}
+
+	check.Status = StatusOK
+	switch {
+	case len(topo.GPUs) <= 1:
+		check.Message = "Single GPU; no interconnect to check"
+	case len(islands) == 0:
+		check.Message = fmt.Sprintf("%d GPUs, no NVLink; tensor parallelism runs over PCIe", len(topo.GPUs))
+	case len(islands) == 1 && len(islands[0]) == len(topo.GPUs):
+		check.Message = fmt.Sprintf("%d GPUs, all connected by NVLink", len(topo.GPUs))
+	default:
+		check.Message = fmt.Sprintf("%d GPUs in %d NVLink island(s)", len(topo.GPUs), len(islands))
+	}
+
+	if run.tp > 1 && run.tp <= len(topo.GPUs) {
+		group, err := recommendGPUs(topo, run.gpuInventory(), run.tp)
+		if err != nil {
+			check.Status = StatusWarning
+			details = append(details, err.Error())
+		} else {
+			run.setTPGroup(group)
+			line := fmt.Sprintf("TP=%d: use --gpus %s (%s", run.tp, joinInts(group.GPUs), linkDescription(group.WeakestLink))
+			if group.NUMA != "" {
+				line += ", NUMA " + group.NUMA
+			}
+			details = append(details, line+")")
+			check.Message += fmt.Sprintf("; TP=%d fits on GPU %s", run.tp, joinInts(group.GPUs))
+			switch {
+			case group.WeakestLink == "SYS":
+				check.Status = StatusWarning
+				check.Message = fmt.Sprintf("No TP=%d group avoids crossing CPU sockets; all-reduce will be slow", run.tp)
+				check.Remediation = "Use a --tp no larger than the GPUs of one NUMA node, and scale out with replicas"
+			case group.Busy:
+				check.Status = StatusWarning
+				check.Message = fmt.Sprintf("Fewer than %d idle GPUs; the best TP=%d group includes GPUs in use", run.tp, run.tp)
+				check.Remediation = "Stop the processes shown by nvidia-smi on those GPUs"
+			}
+		}
+	}
+	check.Details = strings.Join(details, "\n")
+	return check
+}
@@ -10,17 +10,20 @@ import (
 	"time"
 
 	"github.com/svngoku/hermes-cli/internal/app"
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/engine"
 	"github.com/svngoku/hermes-cli/internal/ui"
 )
 
 type VerifyResult struct {
-	Endpoint   string `json:"endpoint"`
-	Status     string `json:"status"`
-	ModelsOK   bool   `json:"models_ok"`
-	HealthOK   bool   `json:"health_ok"`
-	ChatOK     bool   `json:"chat_ok,omitempty"`
-	Message    string `json:"message,omitempty"`
-	DurationMs int64  `json:"duration_ms"`
+	Endpoint   string        `json:"endpoint"`
+	Status     string        `json:"status"`
+	ModelsOK   bool          `json:"models_ok"`
+	HealthOK   bool          `json:"health_ok"`
+	ChatOK     bool          `json:"chat_ok,omitempty"`
+	Probes     []ProbeResult `json:"probes,omitempty"`
+	Message    string        `json:"message,omitempty"`
+	DurationMs int64         `json:"duration_ms"`
 }
 
 func Verify(ctx *app.AppContext, args []string) error {
@@ -31,6 +34,8 @@ func Verify(ctx *app.AppContext, args []string) error {
 	noVerify := fs.Bool("no-verify", false, "Skip verification (no-op for compatibility)")
 	jsonOutput := fs.Bool("json", false, "Output in JSON format")
 	chat := fs.Bool("chat", false, "Also test chat completion endpoint")
+	engineName := fs.String("engine", "", "Engine serving the endpoint: sglang|vllm|lmdeploy (optional)")
+	model := fs.String("model", "", "Served model name, enables the generation probe")
 	fs.Usage = func() {
 		fmt.Fprintln(ctx.Stdout, "Usage: hermes verify [flags]")
 		fmt.Fprintln(ctx.Stdout)
@@ -49,8 +54,19 @@ func Verify(ctx *app.AppContext, args []string) error {
 		return nil
 	}
 
+	caps := engine.DefaultCapabilities()
+	var probes []engine.Probe
+	if *engineName != "" {
+		eng := engine.Get(config.Engine(*engineName))
+		if eng == nil {
+			return fmt.Errorf("invalid engine: %s (use sglang, vllm or lmdeploy)", *engineName)
+		}
+		caps = eng.Capabilities()
+		probes = eng.ReadinessProbes(config.ServeConfig{Model: *model})
+	}
+
 	base := fmt.Sprintf("http://%s:%d", *host, *port)
-	result := runVerify(ctx, base, time.Duration(*timeout)*time.Second, *chat, *jsonOutput)
+	result := runVerify(ctx, base, caps, probes, time.Duration(*timeout)*time.Second, *chat, *jsonOutput)
 
 	if *jsonOutput {
 		enc := json.NewEncoder(ctx.Stdout)
@@ -64,7 +80,9 @@ func Verify(ctx *app.AppContext, args []string) error {
 	return fmt.Errorf("verification failed: %s", result.Message)
 }
 
-func runVerify(ctx *app.AppContext, base string, timeout time.Duration, testChat, jsonOut bool) VerifyResult {
+// runVerify checks the OpenAI endpoints and, when probes are given, runs the
+// engine's readiness sequence once; any failing probe fails verification.
+func runVerify(ctx *app.AppContext, base string, caps engine.Capabilities, probes []engine.Probe, timeout time.Duration, testChat, jsonOut bool) VerifyResult {
 	start := time.Now()
 	result := VerifyResult{
 		Endpoint: base,
@@ -76,12 +94,20 @@ func runVerify(ctx *app.AppContext, base string, timeout time.Duration, testChat
 		fmt.Fprintln(ctx.Stdout, ui.HR())
 	}
 
+	if ctx.DryRun {
+		planProbes(ctx, "verify", base, verifyProbes(caps, testChat))
+		planProbes(ctx, "verify", base, probes)
+		result.Status = "ok"
+		result.Message = "Dry run"
+		return result
+	}
+
 	client := &http.Client{Timeout: timeout}
 
-	modelsOK := checkModels(ctx, client, base, jsonOut)
+	modelsOK := checkModels(ctx, client, base, caps.ReadinessPath, jsonOut)
 	result.ModelsOK = modelsOK
 
-	healthOK := checkHealth(ctx, client, base, jsonOut)
+	healthOK := checkHealth(ctx, client, base, caps.HealthPath, jsonOut)
 	result.HealthOK = healthOK
 
 	if testChat {
@@ -89,18 +115,42 @@ func runVerify(ctx *app.AppContext, base string, timeout time.Duration, testChat
 		result.ChatOK = chatOK
 	}
 
+	probesOK := true
+	if len(probes) > 0 {
+		result.Probes = checkProbes(ctx.Ctx, base, probes)
+		for _, p := range result.Probes {
+			if !p.Passed {
+				probesOK = false
+			}
+			if jsonOut {
+				continue
+			}
+			if p.Passed {
+				fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("probe %s %s: OK (%s)", p.Name, p.Path, formatMs(p.DurationMs))))
+			} else {
+				fmt.Fprintln(ctx.Stdout, ui.Fail(fmt.Sprintf("probe %s %s: %s", p.Name, p.Path, p.Message)))
+			}
+		}
+	}
+
 	result.DurationMs = time.Since(start).Milliseconds()
 
 	if !jsonOut {
 		fmt.Fprintln(ctx.Stdout, ui.HR())
 	}
 
-	if result.ModelsOK || result.HealthOK {
+	if (result.ModelsOK || result.HealthOK) && probesOK {
 		result.Status = "ok"
 		result.Message = "Server is operational"
 		if !jsonOut {
 			fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("Server operational (%dms)", result.DurationMs)))
 		}
+	} else if result.ModelsOK || result.HealthOK {
+		result.Status = "fail"
+		result.Message = "Server responding but readiness probes failed"
+		if !jsonOut {
+			fmt.Fprintln(ctx.Stdout, ui.Fail(result.Message))
+		}
 	} else {
 		result.Status = "fail"
 		result.Message = "Server not responding"
@@ -112,12 +162,24 @@ func runVerify(ctx *app.AppContext, base string, timeout time.Duration, testChat
 	return result
 }
 
-func checkModels(ctx *app.AppContext, client *http.Client, base string, jsonOut bool) bool {
-	url := base + "/v1/models"
-	resp, err := client.Get(url)
+// verifyProbes describes the requests runVerify makes, for dry-run plans.
+func verifyProbes(caps engine.Capabilities, testChat bool) []engine.Probe {
+	probes := []engine.Probe{
+		{Name: "models", Method: "GET", Path: caps.ReadinessPath},
+		{Name: "health", Method: "GET", Path: caps.HealthPath},
+	}
+	if testChat {
+		probes = append(probes, engine.Probe{Name: "chat", Method: "POST", Path: "/v1/chat/completions",
+			Body: `{"model":"default","messages":[{"role":"user","content":"Return OK"}],"max_tokens":8,"temperature":0}`})
+	}
+	return probes
+}
+
+func checkModels(ctx *app.AppContext, client *http.Client, base, path string, jsonOut bool) bool {
+	resp, err := client.Get(base + path)
 	if err != nil {
 		if !jsonOut {
-			fmt.Fprintln(ctx.Stdout, ui.Fail(fmt.Sprintf("GET /v1/models: %v", err)))
+			fmt.Fprintln(ctx.Stdout, ui.Fail(fmt.Sprintf("GET %s: %v", path, err)))
 		}
 		return false
 	}
@@ -130,24 +192,26 @@ func checkModels(ctx *app.AppContext, client *http.Client, base string, jsonOut
 			if len(preview) > 200 {
 				preview = preview[:200] + "..."
 			}
-			fmt.Fprintln(ctx.Stdout, ui.Ok("GET /v1/models: OK"))
+			fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("GET %s: OK", path)))
 			fmt.Fprintln(ctx.Stdout, "    "+strings.ReplaceAll(preview, "\n", "\n    "))
 		}
 		return true
 	}
 
 	if !jsonOut {
-		fmt.Fprintln(ctx.Stdout, ui.Fail(fmt.Sprintf("GET /v1/models: %d", resp.StatusCode)))
+		fmt.Fprintln(ctx.Stdout, ui.Fail(fmt.Sprintf("GET %s: %d", path, resp.StatusCode)))
 	}
 	return false
 }
 
-func checkHealth(ctx *app.AppContext, client *http.Client, base string, jsonOut bool) bool {
-	url := base + "/health"
-	resp, err := client.Get(url)
+func checkHealth(ctx *app.AppContext, client *http.Client, base, path string, jsonOut bool) bool {
+	if path == "" {
+		return false
+	}
+	resp, err := client.Get(base + path)
 	if err != nil {
 		if !jsonOut {
-			fmt.Fprintln(ctx.Stdout, ui.Warn(fmt.Sprintf("GET /health: %v", err)))
+			fmt.Fprintln(ctx.Stdout, ui.Warn(fmt.Sprintf("GET %s: %v", path, err)))
 		}
 		return false
 	}
@@ -155,13 +219,13 @@ func checkHealth(ctx *app.AppContext, client *http.Client, base string, jsonOut
 
 	if resp.StatusCode == http.StatusOK {
 		if !jsonOut {
-			fmt.Fprintln(ctx.Stdout, ui.Ok("GET /health: OK"))
+			fmt.Fprintln(ctx.Stdout, ui.Ok(fmt.Sprintf("GET %s: OK", path)))
 		}
 		return true
 	}
 
 	if !jsonOut {
-		fmt.Fprintln(ctx.Stdout, ui.Warn(fmt.Sprintf("GET /health: %d", resp.StatusCode)))
+		fmt.Fprintln(ctx.Stdout, ui.Warn(fmt.Sprintf("GET %s: %d", path, resp.StatusCode)))
 	}
 	return false
 }
@@ -3,28 +3,69 @@ package config
 type Engine string
 
 const (
-	EngineSGLang Engine = "sglang"
-	EngineVLLM   Engine = "vllm"
+	EngineSGLang   Engine = "sglang"
+	EngineVLLM     Engine = "vllm"
+	EngineLMDeploy Engine = "lmdeploy"
 )
 
 type InstallMode string
 
 const (
-	InstallSGLang InstallMode = "sglang"
-	InstallVLLM   InstallMode = "vllm"
-	InstallBoth   InstallMode = "both"
-	InstallNone   InstallMode = "none"
+	InstallSGLang   InstallMode = "sglang"
+	InstallVLLM     InstallMode = "vllm"
+	InstallLMDeploy InstallMode = "lmdeploy"
+	InstallBoth     InstallMode = "both"
+	InstallNone     InstallMode = "none"
 )
 
 type ServeConfig struct {
-	Engine    Engine
-	Model     string
-	TP        int
-	Host      string
-	Port      int
-	Daemon    bool
-	ExtraArgs string
-	LogFile   string
+	Engine         Engine
+	Model          string
+	TP             int
+	Host           string
+	Port           int
+	Daemon         bool
+	ExtraArgs      string
+	LogFile        string
+	Backend        string
+	Quantization   string
+	ToolCallParser string
+
+	// GPUs restricts the server to a comma-separated list of device
+	// indexes. Empty means all visible GPUs.
+	GPUs string
+
+	// Runtime runs the engine in a container (docker or podman) instead of
+	// its managed virtualenv. Image, ShmSize and Env apply only then.
+	Runtime string
+	Image   string
+	ShmSize string
+	Env     []string
+
+	// Resources are the limits and CPU placement applied to the engine's
+	// process group.
+	Resources Resources
+
+	// SkipFlagCheck launches without validating flags against the
+	// installed engine's --help output.
+	SkipFlagCheck bool
+
+	// ReadinessTimeout bounds the readiness probes run alongside a
+	// foreground server, in seconds. Zero disables them.
+	ReadinessTimeout int
+}
+
+// Resources holds serve-time process controls. Limits are "unlimited" or a
+// number (bytes for MemLock); CPUs and NUMA are cpulists or "auto", which
+// follows the GPUs' affinity. Empty fields leave the inherited value.
+type Resources struct {
+	NoFile  string
+	MemLock string
+	Core    string
+	Nice    int
+	IONice  string
+	CPUs    string
+	NUMA    string
 }
 
 type DoctorConfig struct {
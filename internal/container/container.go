@@ -0,0 +1,187 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/execx"
+	"github.com/svngoku/hermes-cli/internal/gpu"
+	"github.com/svngoku/hermes-cli/internal/hf"
+)
+
+// Runtime is an OCI container CLI that engines can be run under instead of a
+// managed virtualenv. The runtime binary is looked up on PATH.
+type Runtime string
+
+const (
+	Docker Runtime = "docker"
+	Podman Runtime = "podman"
+)
+
+// containerHFCache is where the engine images expect the Hugging Face cache.
+const containerHFCache = "/root/.cache/huggingface"
+
+var defaultImages = map[config.Engine]string{
+	config.EngineSGLang:   "lmsysorg/sglang:latest",
+	config.EngineVLLM:     "vllm/vllm-openai:latest",
+	config.EngineLMDeploy: "openmmlab/lmdeploy:latest",
+}
+
+// passthroughEnv is forwarded by name so tokens never appear in argv.
+var passthroughEnv = []string{"HF_TOKEN", "HUGGING_FACE_HUB_TOKEN", "HF_ENDPOINT", "HF_HUB_OFFLINE"}
+
+func Parse(name string) (Runtime, error) {
+	switch Runtime(name) {
+	case Docker, Podman:
+		return Runtime(name), nil
+	}
+	return "", fmt.Errorf("invalid runtime: %s (use docker or podman)", name)
+}
+
+// DefaultImage is the official serving image for an engine.
+func DefaultImage(name config.Engine) string {
+	return defaultImages[name]
+}
+
+func (r Runtime) Available(ctx context.Context) bool {
+	return execx.CommandExists(ctx, string(r))
+}
+
+// Spec is a container to start for one engine server.
+type Spec struct {
+	Name       string
+	Image      string
+	Entrypoint string
+	Args       []string
+	Host       string
+	Port       int
+	GPUs       string
+	ShmSize    string
+	Env        []string
+	Mounts     []string
+	Detach     bool
+	// Ulimits are `--ulimit` values such as nofile=65536:65536.
+	Ulimits    []string
+	CPUSet     string
+	CPUSetMems string
+}
+
+// RunArgs builds the `run` invocation for spec. Detached containers are kept
+// after exit so their logs survive a crash; attached ones are removed.
+func (r Runtime) RunArgs(spec Spec) []string {
+	args := []string{"run", "--name", spec.Name}
+	if spec.Detach {
+		args = append(args, "--detach")
+	} else {
+		args = append(args, "--rm")
+	}
+	args = append(args, r.gpuArgs(spec.GPUs)...)
+
+	publish := fmt.Sprintf("%d:%d", spec.Port, spec.Port)
+	if spec.Host != "" && spec.Host != "0.0.0.0" {
+		publish = spec.Host + ":" + publish
+	}
+	args = append(args, "--publish", publish)
+
+	if spec.ShmSize != "" {
+		args = append(args, "--shm-size", spec.ShmSize)
+	}
+	for _, u := range spec.Ulimits {
+		args = append(args, "--ulimit", u)
+	}
+	if spec.CPUSet != "" {
+		args = append(args, "--cpuset-cpus", spec.CPUSet)
+	}
+	if spec.CPUSetMems != "" {
+		args = append(args, "--cpuset-mems", spec.CPUSetMems)
+	}
+	for _, m := range spec.Mounts {
+		args = append(args, "--volume", m)
+	}
+	for _, e := range spec.Env {
+		args = append(args, "--env", e)
+	}
+	if spec.Entrypoint != "" {
+		args = append(args, "--entrypoint", spec.Entrypoint)
+	}
+	args = append(args, spec.Image)
+	return append(args, spec.Args...)
+}
+
+// gpuArgs exposes all GPUs, or the comma-separated device indexes given.
+// Podman uses CDI device names from the NVIDIA container toolkit.
+func (r Runtime) gpuArgs(gpus string) []string {
+	if r == Podman {
+		if gpus == "" {
+			return []string{"--device", "nvidia.com/gpu=all"}
+		}
+		var args []string
+		for _, id := range strings.Split(gpus, ",") {
+			args = append(args, "--device", "nvidia.com/gpu="+strings.TrimSpace(id))
+		}
+		return args
+	}
+	if gpus == "" {
+		return []string{"--gpus", "all"}
+	}
+	return []string{"--gpus", strconv.Quote("device=" + gpus)}
+}
+
+// ServeEnv returns the environment and mounts shared by every engine
+// container: the host's Hugging Face cache, its tokens, and any local model
+// directory, which is mounted read-only at the same path.
+func ServeEnv(cfg config.ServeConfig) (env, mounts []string) {
+	mounts = append(mounts, hf.Home()+":"+containerHFCache)
+	for _, name := range passthroughEnv {
+		if _, ok := os.LookupEnv(name); ok {
+			env = append(env, name)
+		}
+	}
+	if filepath.IsAbs(cfg.Model) {
+		if _, err := os.Stat(cfg.Model); err == nil {
+			mounts = append(mounts, cfg.Model+":"+cfg.Model+":ro")
+		}
+	}
+	env = append(env, gpu.DeviceOrderEnv)
+	env = append(env, cfg.Env...)
+	return env, mounts
+}
+
+// Running reports whether the named container exists and is running.
+func (r Runtime) Running(ctx context.Context, name string) bool {
+	result := execx.Run(execx.ReadOnly(ctx), string(r), "inspect", "--format", "{{.State.Running}}", name)
+	return result.ExitCode == 0 && result.Stdout == "true"
+}
+
+// Stop stops the named container, giving it timeout seconds to exit.
+func (r Runtime) Stop(ctx context.Context, name string, timeout int) error {
+	result := execx.Run(ctx, string(r), "stop", "--time", strconv.Itoa(timeout), name)
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%s stop %s: %s", r, name, result.Stderr)
+	}
+	return nil
+}
+
+// Remove deletes the named container if it exists.
+func (r Runtime) Remove(ctx context.Context, name string) {
+	if execx.Run(execx.ReadOnly(ctx), string(r), "inspect", "--format", "{{.Id}}", name).ExitCode != 0 {
+		return
+	}
+	execx.Run(ctx, string(r), "rm", "--force", name)
+}
+
+func (r Runtime) LogsArgs(name string, follow bool, tail int) []string {
+	args := []string{"logs"}
+	if follow {
+		args = append(args, "--follow")
+	}
+	if tail >= 0 {
+		args = append(args, "--tail", strconv.Itoa(tail))
+	}
+	return append(args, name)
+}
@@ -2,24 +2,133 @@ package engine
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/svngoku/hermes-cli/internal/config"
 )
 
 type Engine interface {
 	Name() string
-	CheckInstalled(ctx context.Context) (bool, string, error)
-	Install(ctx context.Context) error
+	Env() Env
+	Capabilities() Capabilities
+	ReadinessProbes(cfg config.ServeConfig) []Probe
+	CheckInstalled(ctx context.Context) (EnvInfo, error)
+	Install(ctx context.Context, opts InstallOptions) error
+	Uninstall(ctx context.Context) error
 	ServeCommand(cfg config.ServeConfig) (string, []string)
+	// HelpCommand prints the serve command's flags. Its arguments, minus the
+	// trailing --help, prefix those returned by ServeCommand.
+	HelpCommand() (string, []string)
 }
 
+// Capabilities describes the HTTP surface and optional features of an engine
+// so commands don't have to assume every server looks like sglang.
+type Capabilities struct {
+	HealthPath          string   `json:"health_path"`
+	ReadinessPath       string   `json:"readiness_path"`
+	MetricsPath         string   `json:"metrics_path,omitempty"`
+	Quantization        []string `json:"quantization"`
+	LoRA                bool     `json:"lora"`
+	Embeddings          bool     `json:"embeddings"`
+	MultiNode           bool     `json:"multi_node"`
+	SpeculativeDecoding bool     `json:"speculative_decoding"`
+	ToolCallParsers     []string `json:"tool_call_parsers"`
+	// MinComputeCapability is the oldest GPU architecture the engine's
+	// kernels support, e.g. "7.0" for Volta.
+	MinComputeCapability string `json:"min_compute_capability"`
+}
+
+// DefaultCapabilities is used when the engine behind an endpoint is unknown,
+// e.g. `hermes verify` against a server hermes did not start.
+func DefaultCapabilities() Capabilities {
+	return Capabilities{
+		HealthPath:    "/health",
+		ReadinessPath: "/v1/models",
+	}
+}
+
+func (c Capabilities) SupportsQuantization(method string) bool {
+	return contains(c.Quantization, method)
+}
+
+func (c Capabilities) SupportsToolCallParser(parser string) bool {
+	return contains(c.ToolCallParsers, parser)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the engine bound to its default managed environment.
 func Get(name config.Engine) Engine {
+	return WithEnv(name, DefaultEnvPath(name))
+}
+
+// WithEnv returns the engine bound to the environment at envPath.
+func WithEnv(name config.Engine, envPath string) Engine {
+	env := Env{Path: envPath}
 	switch name {
 	case config.EngineSGLang:
-		return &SGLangEngine{}
+		return &SGLangEngine{env: env}
 	case config.EngineVLLM:
-		return &VLLMEngine{}
+		return &VLLMEngine{env: env}
+	case config.EngineLMDeploy:
+		return &LMDeployEngine{env: env}
 	default:
 		return nil
 	}
 }
+
+// Names returns every supported engine name in display order.
+func Names() []config.Engine {
+	return []config.Engine{config.EngineSGLang, config.EngineVLLM, config.EngineLMDeploy}
+}
+
+// All returns every supported engine in display order.
+func All() []Engine {
+	engines := make([]Engine, 0, len(Names()))
+	for _, name := range Names() {
+		engines = append(engines, Get(name))
+	}
+	return engines
+}
+
+// Probe is one stage of an engine's readiness sequence. Stages are checked in
+// order; a server is ready once every stage has passed.
+type Probe struct {
+	Name         string        `json:"name"`
+	Method       string        `json:"method"`
+	Path         string        `json:"path"`
+	Body         string        `json:"body,omitempty"`
+	ExpectStatus int           `json:"expect_status"`
+	ExpectBody   string        `json:"expect_body,omitempty"`
+	Generation   bool          `json:"generation,omitempty"`
+	Timeout      time.Duration `json:"timeout,omitempty"`
+}
+
+// DefaultProbes is the readiness sequence used when the engine is unknown.
+func DefaultProbes() []Probe {
+	return []Probe{
+		{Name: "models", Method: "GET", Path: "/v1/models", ExpectStatus: 200},
+	}
+}
+
+// completionProbe issues a one-token completion, which only succeeds once the
+// weights are loaded and the scheduler is running.
+func completionProbe(model string) Probe {
+	return Probe{
+		Name:         "generate",
+		Method:       "POST",
+		Path:         "/v1/completions",
+		Body:         fmt.Sprintf(`{"model": %q, "prompt": "ping", "max_tokens": 1, "temperature": 0}`, model),
+		ExpectStatus: 200,
+		Generation:   true,
+		Timeout:      60 * time.Second,
+	}
+}
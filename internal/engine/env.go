@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/execx"
+)
+
+// Env is the managed virtualenv that holds a single engine. Each engine gets
+// its own so that their torch pins cannot break one another.
+type Env struct {
+	Path string
+}
+
+// DefaultEnvRoot is the directory under which managed environments live.
+func DefaultEnvRoot() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cache", "hermes", "envs")
+}
+
+// DefaultEnvPath is the managed environment location for an engine.
+func DefaultEnvPath(name config.Engine) string {
+	return filepath.Join(DefaultEnvRoot(), string(name))
+}
+
+// Python and Bin resolve against PATH for the zero Env, which describes an
+// engine image where the tools are installed system-wide.
+func (e Env) Python() string {
+	if e.Path == "" {
+		return "python3"
+	}
+	return e.Bin("python")
+}
+
+func (e Env) Bin(name string) string {
+	if e.Path == "" {
+		return name
+	}
+	return filepath.Join(e.Path, "bin", name)
+}
+
+func (e Env) Exists() bool {
+	_, err := os.Stat(e.Python())
+	return err == nil
+}
+
+// Environ returns the process environment with the virtualenv activated, for
+// engines that spawn helper binaries (ninja, triton) by name. A zero Env,
+// as used for container runs, leaves the environment unchanged.
+func (e Env) Environ() []string {
+	if e.Path == "" {
+		return os.Environ()
+	}
+	return append(os.Environ(),
+		"VIRTUAL_ENV="+e.Path,
+		"PATH="+filepath.Join(e.Path, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"),
+	)
+}
+
+// EnvInfo describes what an engine's environment actually contains, as
+// reported by its own interpreter.
+type EnvInfo struct {
+	Name          string `json:"name"`
+	Installed     bool   `json:"installed"`
+	EnvPath       string `json:"env_path"`
+	Interpreter   string `json:"interpreter,omitempty"`
+	PythonVersion string `json:"python_version,omitempty"`
+	Version       string `json:"version,omitempty"`
+	TorchVersion  string `json:"torch_version,omitempty"`
+	TorchCUDA     string `json:"torch_cuda,omitempty"`
+	// TorchArchs lists the GPU architectures torch's kernels were compiled
+	// for, such as sm_90 or compute_90 (PTX). Empty when CUDA is unusable.
+	TorchArchs    []string `json:"torch_archs,omitempty"`
+	CUDAAvailable bool     `json:"cuda_available"`
+	DeviceCount   int      `json:"device_count"`
+	FlashInfer    string   `json:"flashinfer,omitempty"`
+	Triton        string   `json:"triton,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// inspectScript prints a JSON description of the running interpreter. Every
+// import is guarded so a broken optional package cannot hide the others.
+const inspectScript = `
+import importlib, json, sys
+def ver(name):
+    try:
+        return getattr(importlib.import_module(name), "__version__", "unknown")
+    except Exception:
+        return ""
+info = {"interpreter": sys.executable, "python_version": sys.version.split()[0]}
+info["version"] = ver(sys.argv[1])
+info["flashinfer"] = ver("flashinfer")
+info["triton"] = ver("triton")
+try:
+    import torch
+    info["torch_version"] = torch.__version__
+    info["torch_cuda"] = torch.version.cuda or ""
+    info["cuda_available"] = bool(torch.cuda.is_available())
+    info["device_count"] = torch.cuda.device_count() if info["cuda_available"] else 0
+    info["torch_archs"] = torch.cuda.get_arch_list() if info["cuda_available"] else []
+except Exception:
+    pass
+print(json.dumps(info))
+`
+
+// inspect runs inspectScript with the environment's interpreter. A missing
+// environment is not an error; it is reported as not installed.
+func (e Env) inspect(ctx context.Context, name, module string) (EnvInfo, error) {
+	info := EnvInfo{Name: name, EnvPath: e.Path}
+	// Looked up through the runner rather than stat'ed so that a replayed
+	// cassette sees the recorded machine's environments.
+	if _, err := execx.LookPath(ctx, e.Python()); err != nil {
+		return info, nil
+	}
+	// Importing torch initialises CUDA, which blocks on a wedged driver.
+	result := execx.RunWith(execx.ReadOnly(ctx), execx.Options{Timeout: execx.ImportTimeout}, e.Python(), "-c", inspectScript, module)
+	if result.Err != nil {
+		info.Error = result.Stderr
+		if info.Error == "" {
+			info.Error = result.Err.Error()
+		}
+		return info, fmt.Errorf("inspecting %s: %w", e.Path, result.Err)
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), &info); err != nil {
+		return info, fmt.Errorf("inspecting %s: %w", e.Path, err)
+	}
+	info.Installed = info.Version != ""
+	return info, nil
+}
+
+// packageVersion reads a distribution's version from its metadata, which is
+// much faster than importing it.
+func (e Env) packageVersion(ctx context.Context, dist string) (string, error) {
+	result := execx.RunWith(execx.ReadOnly(ctx), execx.Options{Timeout: execx.ProbeTimeout}, e.Python(), "-c",
+		"import importlib.metadata as m, sys; print(m.version(sys.argv[1]))", dist)
+	if execx.KindOf(result.Err) == execx.KindTimeout {
+		return "", fmt.Errorf("reading %s version: %w", dist, result.Err)
+	}
+	if result.ExitCode != 0 || result.Stdout == "" {
+		return "", fmt.Errorf("%s is not installed in %s", dist, e.Path)
+	}
+	return result.Stdout, nil
+}
@@ -0,0 +1,252 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/execx"
+)
+
+// FlagSpec is one option accepted by an engine's serve command.
+type FlagSpec struct {
+	TakesValue bool   `json:"takes_value"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+	Help       string `json:"help,omitempty"`
+}
+
+// FlagCatalog is the set of serve flags of one installed engine version, as
+// parsed from its --help output.
+type FlagCatalog struct {
+	Engine  string              `json:"engine"`
+	Version string              `json:"version"`
+	Flags   map[string]FlagSpec `json:"flags"`
+}
+
+// flagRename pairs an option with its replacement in another release. An
+// empty replacement means the option was removed and is safe to drop.
+type flagRename struct {
+	old, new   string
+	takesValue bool
+}
+
+// flagRenames lists options whose spelling differs across engine releases.
+// Renames apply in either direction, whichever the installed version knows.
+var flagRenames = map[config.Engine][]flagRename{
+	config.EngineSGLang: {
+		{old: "--tp-size", new: "--tensor-parallel-size", takesValue: true},
+		{old: "--dp-size", new: "--data-parallel-size", takesValue: true},
+	},
+	config.EngineVLLM: {
+		{old: "--guided-decoding-backend", new: "--structured-outputs-config.backend", takesValue: true},
+		// Removed in vllm 0.10; --reasoning-parser alone enables reasoning.
+		{old: "--enable-reasoning"},
+	},
+}
+
+// FlagCacheDir is where parsed flag catalogs are kept, one file per engine
+// version.
+func FlagCacheDir() string {
+	return filepath.Join(filepath.Dir(DefaultEnvRoot()), "flags")
+}
+
+// LoadFlagCatalog returns the catalog for the engine version installed in
+// eng's environment, parsing its --help output on first use.
+func LoadFlagCatalog(ctx context.Context, eng Engine) (*FlagCatalog, error) {
+	version, err := eng.Env().packageVersion(ctx, eng.Name())
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(FlagCacheDir(), fmt.Sprintf("%s-%s.json", eng.Name(), version))
+	if data, err := os.ReadFile(path); err == nil {
+		var catalog FlagCatalog
+		if json.Unmarshal(data, &catalog) == nil && len(catalog.Flags) > 0 {
+			return &catalog, nil
+		}
+	}
+
+	ctx = execx.ReadOnly(ctx)
+	opts := execx.Options{Timeout: execx.ImportTimeout}
+	name, args := eng.HelpCommand()
+	result := execx.RunWith(ctx, opts, name, args...)
+	// Newer vllm releases only summarise their options under plain --help.
+	if strings.Contains(result.Stdout, "--help=all") {
+		args[len(args)-1] = "--help=all"
+		result = execx.RunWith(ctx, opts, name, args...)
+	}
+	if result.Err != nil {
+		return nil, fmt.Errorf("%s --help failed: %w", eng.Name(), result.Err)
+	}
+
+	catalog := &FlagCatalog{Engine: eng.Name(), Version: version, Flags: ParseHelp(result.Stdout)}
+	if len(catalog.Flags) == 0 {
+		return nil, fmt.Errorf("no flags found in %s --help output", eng.Name())
+	}
+	if data, err := json.MarshalIndent(catalog, "", "  "); err == nil {
+		if os.MkdirAll(FlagCacheDir(), 0755) == nil {
+			os.WriteFile(path, data, 0644)
+		}
+	}
+	return catalog, nil
+}
+
+// ParseHelp extracts the options from argparse-style help text. Help lines
+// that follow an option are attached to it.
+func ParseHelp(text string) map[string]FlagSpec {
+	flags := make(map[string]FlagSpec)
+	var current []string
+	var help strings.Builder
+
+	flush := func() {
+		for _, name := range current {
+			spec := flags[name]
+			spec.Help = strings.TrimSpace(help.String())
+			spec.Deprecated = strings.Contains(strings.ToLower(spec.Help), "deprecated")
+			flags[name] = spec
+		}
+		current = nil
+		help.Reset()
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		indented := len(line) > len(strings.TrimLeft(line, " \t"))
+		switch {
+		case indented && strings.HasPrefix(trimmed, "-"):
+			flush()
+			usage, rest := splitHelpColumn(trimmed)
+			for _, alias := range splitAliases(usage) {
+				fields := strings.Fields(alias)
+				name, _, hasValue := strings.Cut(fields[0], "=")
+				if !isFlag(name) {
+					continue
+				}
+				flags[name] = FlagSpec{TakesValue: hasValue || len(fields) > 1}
+				current = append(current, name)
+			}
+			help.WriteString(rest)
+		case indented && trimmed != "" && len(current) > 0:
+			help.WriteString(" " + trimmed)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return flags
+}
+
+// splitHelpColumn separates "--flag VALUE    help text" into its columns.
+func splitHelpColumn(line string) (string, string) {
+	if i := strings.Index(line, "  "); i >= 0 {
+		return line[:i], strings.TrimSpace(line[i:])
+	}
+	return line, ""
+}
+
+// splitAliases splits "-f FOO, --foo FOO" on the commas between options,
+// leaving commas inside choice lists such as {a,b} alone.
+func splitAliases(usage string) []string {
+	var aliases []string
+	for _, part := range strings.Split(usage, ", ") {
+		if len(aliases) > 0 && !strings.HasPrefix(part, "-") {
+			aliases[len(aliases)-1] += ", " + part
+			continue
+		}
+		aliases = append(aliases, part)
+	}
+	return aliases
+}
+
+func isFlag(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	_, err := strconv.ParseFloat(arg, 64)
+	return err != nil
+}
+
+// CheckServeCommand validates the options in args, as returned by
+// eng.ServeCommand, against the catalog. Renamed options are translated and
+// removed ones dropped; both, and deprecated options, produce warnings.
+// Options the engine does not know are an error.
+func (c *FlagCatalog) CheckServeCommand(eng Engine, args []string) ([]string, []string, error) {
+	_, help := eng.HelpCommand()
+	prefix := help[:len(help)-1]
+	start := 0
+	if len(args) >= len(prefix) && strings.Join(args[:len(prefix)], " ") == strings.Join(prefix, " ") {
+		start = len(prefix)
+	}
+
+	out := append([]string{}, args[:start]...)
+	var warnings, unknown []string
+	for i := start; i < len(args); i++ {
+		arg := args[i]
+		if !isFlag(arg) {
+			out = append(out, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+
+		spec, ok := c.Flags[name]
+		if !ok {
+			rename, found := c.rename(config.Engine(eng.Name()), name)
+			switch {
+			case !found:
+				unknown = append(unknown, name)
+				out = append(out, arg)
+				continue
+			case rename.new == "":
+				warnings = append(warnings, fmt.Sprintf("dropping %s: removed in %s %s", name, c.Engine, c.Version))
+				if rename.takesValue && !hasValue && i+1 < len(args) {
+					i++
+				}
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf("translating %s to %s for %s %s", name, rename.new, c.Engine, c.Version))
+			name, spec = rename.new, c.Flags[rename.new]
+		}
+		if spec.Deprecated {
+			warnings = append(warnings, fmt.Sprintf("%s is deprecated in %s %s", name, c.Engine, c.Version))
+		}
+
+		if hasValue {
+			out = append(out, name+"="+value)
+		} else {
+			out = append(out, name)
+		}
+		if spec.TakesValue && !hasValue && i+1 < len(args) {
+			i++
+			out = append(out, args[i])
+		}
+	}
+
+	if len(unknown) > 0 {
+		return nil, warnings, fmt.Errorf("%s %s does not accept %s", c.Engine, c.Version, strings.Join(unknown, ", "))
+	}
+	return out, warnings, nil
+}
+
+// rename finds the spelling of name that the catalog's version accepts.
+func (c *FlagCatalog) rename(name config.Engine, flag string) (flagRename, bool) {
+	for _, r := range flagRenames[name] {
+		switch {
+		case r.new == "" && flag == r.old:
+			return r, true
+		case flag == r.old && c.has(r.new):
+			return r, true
+		case flag == r.new && c.has(r.old):
+			return flagRename{old: r.new, new: r.old, takesValue: r.takesValue}, true
+		}
+	}
+	return flagRename{}, false
+}
+
+func (c *FlagCatalog) has(flag string) bool {
+	_, ok := c.Flags[flag]
+	return ok
+}
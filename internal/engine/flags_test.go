@@ -0,0 +1,226 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/svngoku/hermes-cli/internal/config"
+)
+
+func loadHelp(t *testing.T, name string) map[string]FlagSpec {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ParseHelp(string(data))
+}
+
+func TestParseHelp(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    map[string]FlagSpec
+		absent  []string
+	}{
+		{
+			// Wrapped usage lines, aliases sharing a metavar, and a help
+			// text announcing its own deprecation.
+			fixture: "sglang-0.4.6-help.txt",
+			want: map[string]FlagSpec{
+				"-h":                      {},
+				"--help":                  {},
+				"--model-path":            {TakesValue: true},
+				"--model":                 {TakesValue: true},
+				"--tensor-parallel-size":  {TakesValue: true},
+				"--tp-size":               {TakesValue: true},
+				"--dp-size":               {TakesValue: true},
+				"--quantization":          {TakesValue: true},
+				"--trust-remote-code":     {},
+				"--disable-radix-cache":   {},
+				"--enable-flashinfer-mla": {Deprecated: true},
+			},
+			absent: []string{"--attention-backend"},
+		},
+		{
+			// --help=all: grouped sections, --no- boolean pairs, short
+			// aliases after choice lists, and [DEPRECATED] markers.
+			fixture: "vllm-0.11.0-help.txt",
+			want: map[string]FlagSpec{
+				"--api-key":                           {TakesValue: true},
+				"--host":                              {TakesValue: true},
+				"--enable-auto-tool-choice":           {},
+				"--no-enable-auto-tool-choice":        {},
+				"--tool-call-parser":                  {TakesValue: true},
+				"--disable-log-requests":              {Deprecated: true},
+				"--no-disable-log-requests":           {Deprecated: true},
+				"--quantization":                      {TakesValue: true},
+				"-q":                                  {TakesValue: true},
+				"--tensor-parallel-size":              {TakesValue: true},
+				"-tp":                                 {TakesValue: true},
+				"--structured-outputs-config.backend": {TakesValue: true},
+			},
+			absent: []string{"model_tag", "--enable-reasoning", "--guided-decoding-backend"},
+		},
+		{
+			// Positional help wrapping onto a line that starts with "-",
+			// and nargs="*" options.
+			fixture: "lmdeploy-0.9.2-help.txt",
+			want: map[string]FlagSpec{
+				"--server-name":   {TakesValue: true},
+				"--server-port":   {TakesValue: true},
+				"--allow-origins": {TakesValue: true},
+				"--adapters":      {TakesValue: true},
+				"--backend":       {TakesValue: true},
+				"--tp":            {TakesValue: true},
+				"--model-format":  {TakesValue: true},
+			},
+			absent: []string{"model_path", "-", "--tensor-parallel-size"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			flags := loadHelp(t, tt.fixture)
+			for name, want := range tt.want {
+				got, ok := flags[name]
+				if !ok {
+					t.Errorf("%s missing", name)
+					continue
+				}
+				got.Help = ""
+				if got != want {
+					t.Errorf("%s = %+v, want %+v", name, got, want)
+				}
+			}
+			for _, name := range tt.absent {
+				if _, ok := flags[name]; ok {
+					t.Errorf("%s parsed as a flag", name)
+				}
+			}
+		})
+	}
+}
+
+func TestParseHelpJoinsWrappedHelp(t *testing.T) {
+	flags := loadHelp(t, "sglang-0.4.6-help.txt")
+	want := "The fraction of the memory used for static allocation (model weights and KV cache memory pool). Use a smaller value if you see out-of-memory errors."
+	if got := flags["--mem-fraction-static"].Help; got != want {
+		t.Errorf("help = %q, want %q", got, want)
+	}
+	if got := flags["--host"].Help; got != "The host of the HTTP server." {
+		t.Errorf("--host help = %q", got)
+	}
+}
+
+func TestCheckServeCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		engine   config.Engine
+		fixture  string
+		version  string
+		args     []string
+		want     []string
+		warnings []string
+		err      string
+	}{
+		{
+			name:    "sglang serve command",
+			engine:  config.EngineSGLang,
+			fixture: "sglang-0.4.6-help.txt",
+			version: "0.4.6",
+			args:    []string{"-m", "sglang.launch_server", "--model-path", "m", "--trust-remote-code", "--tp-size", "2", "--host", "0.0.0.0", "--port", "30000"},
+			want:    []string{"-m", "sglang.launch_server", "--model-path", "m", "--trust-remote-code", "--tp-size", "2", "--host", "0.0.0.0", "--port", "30000"},
+		},
+		{
+			name:     "sglang deprecated option",
+			engine:   config.EngineSGLang,
+			fixture:  "sglang-0.4.6-help.txt",
+			version:  "0.4.6",
+			args:     []string{"-m", "sglang.launch_server", "--model-path", "m", "--enable-flashinfer-mla"},
+			want:     []string{"-m", "sglang.launch_server", "--model-path", "m", "--enable-flashinfer-mla"},
+			warnings: []string{"--enable-flashinfer-mla is deprecated in sglang 0.4.6"},
+		},
+		{
+			name:    "sglang unknown option",
+			engine:  config.EngineSGLang,
+			fixture: "sglang-0.4.6-help.txt",
+			version: "0.4.6",
+			args:    []string{"-m", "sglang.launch_server", "--model-path", "m", "--max-num-seqs", "8"},
+			err:     "sglang 0.4.6 does not accept --max-num-seqs",
+		},
+		{
+			name:    "vllm serve command",
+			engine:  config.EngineVLLM,
+			fixture: "vllm-0.11.0-help.txt",
+			version: "0.11.0",
+			args:    []string{"serve", "m", "--host", "0.0.0.0", "--port", "8000", "--tensor-parallel-size", "2", "--trust-remote-code", "--enable-auto-tool-choice", "--tool-call-parser", "hermes", "--seed", "-1"},
+			want:    []string{"serve", "m", "--host", "0.0.0.0", "--port", "8000", "--tensor-parallel-size", "2", "--trust-remote-code", "--enable-auto-tool-choice", "--tool-call-parser", "hermes", "--seed", "-1"},
+		},
+		{
+			name:     "vllm renamed option",
+			engine:   config.EngineVLLM,
+			fixture:  "vllm-0.11.0-help.txt",
+			version:  "0.11.0",
+			args:     []string{"serve", "m", "--guided-decoding-backend", "xgrammar", "--guided-decoding-backend=outlines"},
+			want:     []string{"serve", "m", "--structured-outputs-config.backend", "xgrammar", "--structured-outputs-config.backend=outlines"},
+			warnings: []string{"translating --guided-decoding-backend to --structured-outputs-config.backend for vllm 0.11.0", "translating --guided-decoding-backend to --structured-outputs-config.backend for vllm 0.11.0"},
+		},
+		{
+			name:     "vllm removed option",
+			engine:   config.EngineVLLM,
+			fixture:  "vllm-0.11.0-help.txt",
+			version:  "0.11.0",
+			args:     []string{"serve", "m", "--enable-reasoning", "--reasoning-parser", "deepseek_r1"},
+			want:     []string{"serve", "m", "--reasoning-parser", "deepseek_r1"},
+			warnings: []string{"dropping --enable-reasoning: removed in vllm 0.11.0"},
+		},
+		{
+			name:     "vllm deprecated option is kept",
+			engine:   config.EngineVLLM,
+			fixture:  "vllm-0.11.0-help.txt",
+			version:  "0.11.0",
+			args:     []string{"serve", "m", "--disable-log-requests"},
+			want:     []string{"serve", "m", "--disable-log-requests"},
+			warnings: []string{"--disable-log-requests is deprecated in vllm 0.11.0"},
+		},
+		{
+			name:    "lmdeploy serve command",
+			engine:  config.EngineLMDeploy,
+			fixture: "lmdeploy-0.9.2-help.txt",
+			version: "0.9.2",
+			args:    []string{"serve", "api_server", "m", "--backend", "turbomind", "--tp", "2", "--server-name", "0.0.0.0", "--server-port", "23333", "--model-format", "awq"},
+			want:    []string{"serve", "api_server", "m", "--backend", "turbomind", "--tp", "2", "--server-name", "0.0.0.0", "--server-port", "23333", "--model-format", "awq"},
+		},
+		{
+			name:    "lmdeploy has no renames",
+			engine:  config.EngineLMDeploy,
+			fixture: "lmdeploy-0.9.2-help.txt",
+			version: "0.9.2",
+			args:    []string{"serve", "api_server", "m", "--tensor-parallel-size", "2", "--port", "8000"},
+			err:     "lmdeploy 0.9.2 does not accept --tensor-parallel-size, --port",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := &FlagCatalog{Engine: string(tt.engine), Version: tt.version, Flags: loadHelp(t, tt.fixture)}
+			got, warnings, err := catalog.CheckServeCommand(WithEnv(tt.engine, "/envs/"+string(tt.engine)), tt.args)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/svngoku/hermes-cli/internal/config"
+	"github.com/svngoku/hermes-cli/internal/execx"
+)
+
+// defaultSpecs are the version floors used when no version is requested.
+var defaultSpecs = map[config.Engine]string{
+	config.EngineSGLang:   ">=0.4",
+	config.EngineVLLM:     ">=0.6",
+	config.EngineLMDeploy: ">=0.6",
+}
+
+// InstallOptions controls how an engine package is resolved by uv.
+type InstallOptions struct {
+	// Version is either a bare version ("0.4.6") or a full PEP 440
+	// specifier (">=0.4,<0.5"). Empty means the engine's default floor.
+	Version        string
+	Extras         []string
+	ExtraIndexURLs []string
+	Constraints    []string
+	Upgrade        bool
+
+	// Wheelhouse, when set, installs solely from the local directory of
+	// wheels with uv in offline mode.
+	Wheelhouse string
+
+	// OnLine, when set, receives uv's output line by line as it is
+	// produced instead of it being buffered until the process exits.
+	OnLine execx.LineFunc
+}
+
+// Pinned reports whether the options request a specific version.
+func (o InstallOptions) Pinned() bool {
+	return o.Version != ""
+}
+
+// Requirement builds the requirement string for pkg, e.g. "sglang[all]==0.4.6".
+func (o InstallOptions) Requirement(pkg, defaultSpec string) string {
+	req := pkg
+	if len(o.Extras) > 0 {
+		req += "[" + strings.Join(o.Extras, ",") + "]"
+	}
+	if o.Version == "" {
+		return req + defaultSpec
+	}
+	return req + NormalizeVersionSpec(o.Version)
+}
+
+// RequirementFor is the requirement string hermes installs for an engine.
+func RequirementFor(name config.Engine, opts InstallOptions) string {
+	return opts.Requirement(string(name), defaultSpecs[name])
+}
+
+// NormalizeVersionSpec turns a bare version into an exact pin and leaves
+// anything that already starts with a comparison operator untouched.
+func NormalizeVersionSpec(v string) string {
+	v = strings.TrimSpace(v)
+	if v == "" || strings.ContainsAny(v[:1], "=<>!~") {
+		return v
+	}
+	return "==" + v
+}
+
+// pipInstallArgs returns the `uv pip install` arguments that install req
+// into the environment env.
+func pipInstallArgs(env Env, req string, opts InstallOptions) []string {
+	args := []string{"pip", "install", "--python", env.Python()}
+	if opts.Upgrade || !opts.Pinned() {
+		args = append(args, "-U")
+	}
+	if opts.Wheelhouse != "" {
+		args = append(args, "--offline", "--no-index", "--find-links", opts.Wheelhouse)
+	} else {
+		for _, url := range opts.ExtraIndexURLs {
+			args = append(args, "--extra-index-url", url)
+		}
+	}
+	for _, c := range opts.Constraints {
+		args = append(args, "--constraint", c)
+	}
+	return append(args, req)
+}
+
+// pipErrorLines is how much of uv's output a failed streaming install keeps
+// for its error, enough for a resolver conflict or a missing index.
+const pipErrorLines = 20
+
+// runPip runs uv with args, streaming to opts.OnLine when it is set.
+func runPip(ctx context.Context, opts InstallOptions, args []string) error {
+	if opts.OnLine != nil {
+		stream := execx.Stream(ctx, execx.StreamOptions{OnLine: opts.OnLine, TailLines: pipErrorLines}, "uv", args...)
+		if stream.Err != nil {
+			if tail := stream.Tail.String(); tail != "" {
+				return fmt.Errorf("%w\n%s", stream.Err, tail)
+			}
+		}
+		return stream.Err
+	}
+	result := execx.Run(ctx, "uv", args...)
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%s", result.Stderr)
+	}
+	return nil
+}
+
+// pipUninstall removes pkg from the environment env.
+func pipUninstall(ctx context.Context, env Env, pkg string) error {
+	result := execx.Run(ctx, "uv", "pip", "uninstall", "--python", env.Python(), pkg)
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to uninstall %s: %s", pkg, result.Stderr)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/svngoku/hermes-cli/internal/config"
+)
+
+type LMDeployEngine struct {
+	env Env
+}
+
+func (e *LMDeployEngine) Name() string {
+	return "lmdeploy"
+}
+
+func (e *LMDeployEngine) Env() Env {
+	return e.env
+}
+
+func (e *LMDeployEngine) Capabilities() Capabilities {
+	return Capabilities{
+		HealthPath:           "/health",
+		ReadinessPath:        "/v1/models",
+		MetricsPath:          "/metrics",
+		Quantization:         []string{"awq", "gptq"},
+		LoRA:                 true,
+		ToolCallParsers:      []string{"internlm", "qwen", "qwen3", "llama3"},
+		MinComputeCapability: "7.0",
+	}
+}
+
+// ReadinessProbes ends with a one-token completion; the api_server answers
+// /v1/models as soon as it binds, before TurboMind has built its engine.
+func (e *LMDeployEngine) ReadinessProbes(cfg config.ServeConfig) []Probe {
+	probes := []Probe{
+		{Name: "health", Method: "GET", Path: "/health", ExpectStatus: 200},
+		{Name: "models", Method: "GET", Path: "/v1/models", ExpectStatus: 200, ExpectBody: cfg.Model},
+	}
+	if cfg.Model != "" {
+		probes = append(probes, completionProbe(cfg.Model))
+	}
+	return probes
+}
+
+func (e *LMDeployEngine) CheckInstalled(ctx context.Context) (EnvInfo, error) {
+	return e.env.inspect(ctx, e.Name(), "lmdeploy")
+}
+
+func (e *LMDeployEngine) Install(ctx context.Context, opts InstallOptions) error {
+	if err := runPip(ctx, opts, pipInstallArgs(e.env, RequirementFor(config.EngineLMDeploy, opts), opts)); err != nil {
+		return fmt.Errorf("failed to install lmdeploy: %w", err)
+	}
+	return nil
+}
+
+func (e *LMDeployEngine) Uninstall(ctx context.Context) error {
+	return pipUninstall(ctx, e.env, "lmdeploy")
+}
+
+func (e *LMDeployEngine) ServeCommand(cfg config.ServeConfig) (string, []string) {
+	backend := cfg.Backend
+	if backend == "" {
+		backend = "turbomind"
+	}
+	args := []string{
+		"serve", "api_server", cfg.Model,
+		"--backend", backend,
+		"--tp", strconv.Itoa(cfg.TP),
+		"--server-name", cfg.Host,
+		"--server-port", strconv.Itoa(cfg.Port),
+	}
+	// lmdeploy takes pre-quantized weights via --model-format rather than a
+	// runtime quantization switch.
+	if cfg.Quantization != "" {
+		args = append(args, "--model-format", cfg.Quantization)
+	}
+	if cfg.ToolCallParser != "" {
+		args = append(args, "--tool-call-parser", cfg.ToolCallParser)
+	}
+	if cfg.ExtraArgs != "" {
+		args = append(args, strings.Fields(cfg.ExtraArgs)...)
+	}
+	return e.env.Bin("lmdeploy"), args
+}
+
+func (e *LMDeployEngine) HelpCommand() (string, []string) {
+	return e.env.Bin("lmdeploy"), []string{"serve", "api_server", "--help"}
+}
@@ -4,47 +4,90 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/svngoku/hermes-cli/internal/config"
-	"github.com/svngoku/hermes-cli/internal/execx"
 )
 
-type SGLangEngine struct{}
+type SGLangEngine struct {
+	env Env
+}
 
 func (e *SGLangEngine) Name() string {
 	return "sglang"
 }
 
-func (e *SGLangEngine) CheckInstalled(ctx context.Context) (bool, string, error) {
-	result := execx.Run(ctx, "uv", "run", "python", "-c", "import sglang; print(sglang.__version__)")
-	if result.ExitCode == 0 {
-		return true, result.Stdout, nil
+func (e *SGLangEngine) Env() Env {
+	return e.env
+}
+
+func (e *SGLangEngine) Capabilities() Capabilities {
+	return Capabilities{
+		HealthPath:    "/health",
+		ReadinessPath: "/v1/models",
+		MetricsPath:   "/metrics",
+		Quantization: []string{
+			"awq", "gptq", "fp8", "marlin", "bitsandbytes", "gguf",
+			"w8a8_int8", "w8a8_fp8", "modelopt",
+		},
+		LoRA:                true,
+		Embeddings:          true,
+		MultiNode:           true,
+		SpeculativeDecoding: true,
+		ToolCallParsers: []string{
+			"qwen25", "mistral", "llama3", "deepseekv3", "pythonic", "kimi_k2",
+		},
+		MinComputeCapability: "7.5",
 	}
+}
 
-	result = execx.Run(ctx, "python", "-c", "import sglang; print(sglang.__version__)")
-	if result.ExitCode == 0 {
-		return true, result.Stdout, nil
+// ReadinessProbes waits for the tokenizer manager, then the loaded model, and
+// finally /health_generate, which runs a real decode step inside the server.
+func (e *SGLangEngine) ReadinessProbes(cfg config.ServeConfig) []Probe {
+	return []Probe{
+		{Name: "health", Method: "GET", Path: "/health", ExpectStatus: 200},
+		{Name: "model_info", Method: "GET", Path: "/get_model_info", ExpectStatus: 200, ExpectBody: "model_path"},
+		{Name: "health_generate", Method: "GET", Path: "/health_generate", ExpectStatus: 200, Generation: true, Timeout: 60 * time.Second},
 	}
+}
 
-	return false, "", nil
+func (e *SGLangEngine) CheckInstalled(ctx context.Context) (EnvInfo, error) {
+	return e.env.inspect(ctx, e.Name(), "sglang")
 }
 
-func (e *SGLangEngine) Install(ctx context.Context) error {
-	result := execx.Run(ctx, "uv", "pip", "install", "-U", "sglang>=0.4")
-	if result.ExitCode != 0 {
-		return fmt.Errorf("failed to install sglang: %s", result.Stderr)
+func (e *SGLangEngine) Install(ctx context.Context, opts InstallOptions) error {
+	if err := runPip(ctx, opts, pipInstallArgs(e.env, RequirementFor(config.EngineSGLang, opts), opts)); err != nil {
+		return fmt.Errorf("failed to install sglang: %w", err)
 	}
 	return nil
 }
 
+func (e *SGLangEngine) Uninstall(ctx context.Context) error {
+	return pipUninstall(ctx, e.env, "sglang")
+}
+
 func (e *SGLangEngine) ServeCommand(cfg config.ServeConfig) (string, []string) {
 	args := []string{
-		"run", "python", "-m", "sglang.launch_server",
+		"-m", "sglang.launch_server",
 		"--model-path", cfg.Model,
 		"--trust-remote-code",
 		"--tp-size", strconv.Itoa(cfg.TP),
 		"--host", cfg.Host,
 		"--port", strconv.Itoa(cfg.Port),
 	}
-	return "uv", args
+	if cfg.Quantization != "" {
+		args = append(args, "--quantization", cfg.Quantization)
+	}
+	if cfg.ToolCallParser != "" {
+		args = append(args, "--tool-call-parser", cfg.ToolCallParser)
+	}
+	if cfg.ExtraArgs != "" {
+		args = append(args, strings.Fields(cfg.ExtraArgs)...)
+	}
+	return e.env.Python(), args
+}
+
+func (e *SGLangEngine) HelpCommand() (string, []string) {
+	return e.env.Python(), []string{"-m", "sglang.launch_server", "--help"}
 }
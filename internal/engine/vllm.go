@@ -7,52 +7,90 @@ import (
 	"strings"
 
 	"github.com/svngoku/hermes-cli/internal/config"
-	"github.com/svngoku/hermes-cli/internal/execx"
 )
 
-type VLLMEngine struct{}
+type VLLMEngine struct {
+	env Env
+}
 
 func (e *VLLMEngine) Name() string {
 	return "vllm"
 }
 
-func (e *VLLMEngine) CheckInstalled(ctx context.Context) (bool, string, error) {
-	result := execx.Run(ctx, "uv", "run", "python", "-c", "import vllm; print(vllm.__version__)")
-	if result.ExitCode == 0 {
-		return true, result.Stdout, nil
-	}
+func (e *VLLMEngine) Env() Env {
+	return e.env
+}
 
-	result = execx.Run(ctx, "python", "-c", "import vllm; print(vllm.__version__)")
-	if result.ExitCode == 0 {
-		return true, result.Stdout, nil
+func (e *VLLMEngine) Capabilities() Capabilities {
+	return Capabilities{
+		HealthPath:    "/health",
+		ReadinessPath: "/v1/models",
+		MetricsPath:   "/metrics",
+		Quantization: []string{
+			"awq", "awq_marlin", "gptq", "gptq_marlin", "fp8", "marlin",
+			"bitsandbytes", "gguf", "compressed-tensors", "experts_int8",
+		},
+		LoRA:                true,
+		Embeddings:          true,
+		MultiNode:           true,
+		SpeculativeDecoding: true,
+		ToolCallParsers: []string{
+			"hermes", "mistral", "llama3_json", "llama4_pythonic", "internlm",
+			"granite", "jamba", "pythonic", "deepseek_v3", "qwen3_coder",
+		},
+		MinComputeCapability: "7.0",
 	}
+}
 
-	result = execx.Run(ctx, "vllm", "--version")
-	if result.ExitCode == 0 {
-		return true, result.Stdout, nil
+// ReadinessProbes follows /v1/models with a one-token completion because the
+// model list can be served before the weights have finished loading.
+func (e *VLLMEngine) ReadinessProbes(cfg config.ServeConfig) []Probe {
+	probes := []Probe{
+		{Name: "health", Method: "GET", Path: "/health", ExpectStatus: 200},
+		{Name: "version", Method: "GET", Path: "/version", ExpectStatus: 200, ExpectBody: "version"},
+		{Name: "models", Method: "GET", Path: "/v1/models", ExpectStatus: 200, ExpectBody: cfg.Model},
+	}
+	if cfg.Model != "" {
+		probes = append(probes, completionProbe(cfg.Model))
 	}
+	return probes
+}
 
-	return false, "", nil
+func (e *VLLMEngine) CheckInstalled(ctx context.Context) (EnvInfo, error) {
+	return e.env.inspect(ctx, e.Name(), "vllm")
 }
 
-func (e *VLLMEngine) Install(ctx context.Context) error {
-	result := execx.Run(ctx, "uv", "pip", "install", "-U", "vllm>=0.6")
-	if result.ExitCode != 0 {
-		return fmt.Errorf("failed to install vllm: %s", result.Stderr)
+func (e *VLLMEngine) Install(ctx context.Context, opts InstallOptions) error {
+	if err := runPip(ctx, opts, pipInstallArgs(e.env, RequirementFor(config.EngineVLLM, opts), opts)); err != nil {
+		return fmt.Errorf("failed to install vllm: %w", err)
 	}
 	return nil
 }
 
+func (e *VLLMEngine) Uninstall(ctx context.Context) error {
+	return pipUninstall(ctx, e.env, "vllm")
+}
+
 func (e *VLLMEngine) ServeCommand(cfg config.ServeConfig) (string, []string) {
 	args := []string{
-		"run", "vllm", "serve", cfg.Model,
+		"serve", cfg.Model,
 		"--host", cfg.Host,
 		"--port", strconv.Itoa(cfg.Port),
 		"--tensor-parallel-size", strconv.Itoa(cfg.TP),
 		"--trust-remote-code",
 	}
+	if cfg.Quantization != "" {
+		args = append(args, "--quantization", cfg.Quantization)
+	}
+	if cfg.ToolCallParser != "" {
+		args = append(args, "--enable-auto-tool-choice", "--tool-call-parser", cfg.ToolCallParser)
+	}
 	if cfg.ExtraArgs != "" {
 		args = append(args, strings.Fields(cfg.ExtraArgs)...)
 	}
-	return "uv", args
+	return e.env.Bin("vllm"), args
+}
+
+func (e *VLLMEngine) HelpCommand() (string, []string) {
+	return e.env.Bin("vllm"), []string{"serve", "--help"}
 }
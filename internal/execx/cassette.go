@@ -0,0 +1,281 @@
+package execx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotRecorded is returned in replay mode for a command the cassette does
+// not contain.
+var ErrNotRecorded = errors.New("command not recorded in cassette")
+
+// Cassette is a recording of the external commands one hermes invocation
+// ran. The user's home directory is stored as $HOME so a cassette replays on
+// another machine.
+type Cassette struct {
+	CreatedAt time.Time `json:"created_at"`
+	Entries   []Entry   `json:"entries"`
+}
+
+// Entry is one recorded call. Kind is "run", "stream", "lookpath" or "http";
+// stream entries keep their output in Stdout and Stderr, http entries the
+// method in Name, the URL in Args, the body in Stdout and the status code in
+// ExitCode.
+type Entry struct {
+	Kind     string   `json:"kind"`
+	Name     string   `json:"name"`
+	Args     []string `json:"args,omitempty"`
+	Stdout   string   `json:"stdout,omitempty"`
+	Stderr   string   `json:"stderr,omitempty"`
+	ExitCode int      `json:"exit_code"`
+	Error    string   `json:"error,omitempty"`
+	// ErrorKind is the execx.ErrorKind of Error, so replays keep it typed.
+	ErrorKind ErrorKind `json:"error_kind,omitempty"`
+	Path      string    `json:"path,omitempty"`
+}
+
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Recorder runs commands with another runner and records every call.
+type Recorder struct {
+	inner    Runner
+	path     string
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+func NewRecorder(inner Runner, path string) *Recorder {
+	return &Recorder{inner: inner, path: path, cassette: Cassette{CreatedAt: time.Now().UTC()}}
+}
+
+func (r *Recorder) add(e Entry) {
+	e.Name = redactHome(e.Name)
+	e.Args = redactArgs(e.Args)
+	e.Stdout = redactHome(e.Stdout)
+	e.Stderr = redactHome(e.Stderr)
+	e.Error = redactHome(e.Error)
+	e.Path = redactHome(e.Path)
+	r.mu.Lock()
+	r.cassette.Entries = append(r.cassette.Entries, e)
+	r.mu.Unlock()
+}
+
+func (r *Recorder) Run(ctx context.Context, name string, args ...string) Result {
+	result := r.inner.Run(ctx, name, args...)
+	e := Entry{Kind: "run", Name: name, Args: args, Stdout: result.Stdout, Stderr: result.Stderr, ExitCode: result.ExitCode}
+	if result.Err != nil {
+		e.Error, e.ErrorKind = result.Err.Error(), KindOf(result.Err)
+	}
+	r.add(e)
+	return result
+}
+
+func (r *Recorder) RunWithStreaming(ctx context.Context, stdout, stderr io.Writer, name string, args ...string) error {
+	var outBuf, errBuf bytes.Buffer
+	err := r.inner.RunWithStreaming(ctx, io.MultiWriter(stdout, &outBuf), io.MultiWriter(stderr, &errBuf), name, args...)
+	e := Entry{Kind: "stream", Name: name, Args: args, Stdout: outBuf.String(), Stderr: errBuf.String()}
+	if err != nil {
+		e.Error, e.ErrorKind = err.Error(), KindOf(err)
+		e.ExitCode = -1
+		var execErr *Error
+		if errors.As(err, &execErr) {
+			e.ExitCode = execErr.ExitCode
+		}
+	}
+	r.add(e)
+	return err
+}
+
+func (r *Recorder) CommandExists(name string) bool {
+	_, err := r.LookPath(name)
+	return err == nil
+}
+
+func (r *Recorder) LookPath(name string) (string, error) {
+	path, err := r.inner.LookPath(name)
+	e := Entry{Kind: "lookpath", Name: name, Path: path}
+	if err != nil {
+		e.Error = err.Error()
+		e.ExitCode = -1
+	}
+	r.add(e)
+	return path, err
+}
+
+// RoundTrip sends req with the default transport and records the response,
+// so HTTP lookups replay with the commands. Request headers, which may carry
+// tokens, are not recorded.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	e := Entry{Kind: "http", Name: req.Method, Args: []string{req.URL.String()}}
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		e.Error, e.ExitCode = err.Error(), -1
+		r.add(e)
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	e.Stdout, e.ExitCode = string(body), resp.StatusCode
+	r.add(e)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// Save writes the cassette to its file.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0644)
+}
+
+// Replayer answers commands from a cassette without running anything. Calls
+// are matched on kind, name and arguments and consumed in order; once a call's
+// recordings are used up, the last one is repeated.
+type Replayer struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     map[int]bool
+}
+
+func NewReplayer(c *Cassette) *Replayer {
+	return &Replayer{cassette: c, used: make(map[int]bool)}
+}
+
+func (r *Replayer) find(kind, name string, args []string) (Entry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name, key := redactHome(name), strings.Join(redactArgs(args), "\x00")
+	last := -1
+	for i, e := range r.cassette.Entries {
+		if e.Kind != kind || e.Name != name || strings.Join(e.Args, "\x00") != key {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return e, true
+		}
+		last = i
+	}
+	if last < 0 {
+		return Entry{}, false
+	}
+	return r.cassette.Entries[last], true
+}
+
+func (r *Replayer) Run(ctx context.Context, name string, args ...string) Result {
+	e, ok := r.find("run", name, args)
+	if !ok {
+		return Result{ExitCode: -1, Stderr: ErrNotRecorded.Error(), Err: ErrNotRecorded}
+	}
+	result := Result{Stdout: expandHome(e.Stdout), Stderr: expandHome(e.Stderr), ExitCode: e.ExitCode}
+	result.Err = e.replayError()
+	return result
+}
+
+func (r *Replayer) RunWithStreaming(ctx context.Context, stdout, stderr io.Writer, name string, args ...string) error {
+	e, ok := r.find("stream", name, args)
+	if !ok {
+		return ErrNotRecorded
+	}
+	io.WriteString(stdout, expandHome(e.Stdout))
+	io.WriteString(stderr, expandHome(e.Stderr))
+	return e.replayError()
+}
+
+// replayError rebuilds the recorded error, typed when its kind was recorded.
+func (e Entry) replayError() error {
+	if e.Error == "" {
+		return nil
+	}
+	msg := expandHome(e.Error)
+	if e.ErrorKind == "" {
+		return errors.New(msg)
+	}
+	return &Error{Kind: e.ErrorKind, Command: expandHome(e.Name), ExitCode: e.ExitCode, Stderr: lastLine(expandHome(e.Stderr)), Err: errors.New(msg)}
+}
+
+// RoundTrip answers req with its recorded response.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	e, ok := r.find("http", req.Method, []string{req.URL.String()})
+	if !ok {
+		return nil, ErrNotRecorded
+	}
+	if e.Error != "" {
+		return nil, errors.New(expandHome(e.Error))
+	}
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", e.ExitCode, http.StatusText(e.ExitCode)),
+		StatusCode: e.ExitCode,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(expandHome(e.Stdout))),
+		Request:    req,
+	}, nil
+}
+
+func (r *Replayer) CommandExists(name string) bool {
+	_, err := r.LookPath(name)
+	return err == nil
+}
+
+func (r *Replayer) LookPath(name string) (string, error) {
+	e, ok := r.find("lookpath", name, nil)
+	if !ok || e.Error != "" {
+		return "", &Error{Kind: KindNotFound, Command: name, ExitCode: -1, Err: exec.ErrNotFound}
+	}
+	return expandHome(e.Path), nil
+}
+
+func redactArgs(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	out := make([]string, len(args))
+	for i, a := range args {
+		out[i] = redactHome(a)
+	}
+	return out
+}
+
+func redactHome(s string) string {
+	if home, err := os.UserHomeDir(); err == nil && home != "" && home != "/" {
+		return strings.ReplaceAll(s, home, "$HOME")
+	}
+	return s
+}
+
+func expandHome(s string) string {
+	if home, err := os.UserHomeDir(); err == nil {
+		return strings.ReplaceAll(s, "$HOME", home)
+	}
+	return s
+}
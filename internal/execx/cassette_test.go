@@ -0,0 +1,169 @@
+package execx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassetteRoundTrip(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	python := filepath.Join(home, ".cache", "hermes", "envs", "vllm", "bin", "python")
+	importErr := &Error{Kind: KindExit, Command: python, ExitCode: 1, Stderr: "ModuleNotFoundError: No module named 'vllm'", Err: errors.New("exit status 1")}
+
+	smiCalls := 0
+	inner := &fakeRunner{
+		run: func(ctx context.Context, cmd string) Result {
+			switch cmd {
+			case "nvidia-smi -L":
+				smiCalls++
+				return Result{Stdout: strings.Repeat("GPU\n", smiCalls)}
+			case python + " -c import vllm":
+				return Result{Stderr: "ModuleNotFoundError: No module named 'vllm'", ExitCode: 1, Err: importErr}
+			case "uv pip install --python " + python + " vllm":
+				return Result{Stdout: "Resolved 42 packages\n", Stderr: "Installed 42 packages\n"}
+			}
+			t.Fatalf("unexpected command %q", cmd)
+			return Result{}
+		},
+		paths: map[string]string{"uv": filepath.Join(home, ".local", "bin", "uv")},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"Qwen/Qwen3-8B"}`)
+	}))
+	modelURL := srv.URL + "/api/models/Qwen/Qwen3-8B"
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec := NewRecorder(inner, path)
+	ctx := context.Background()
+	rec.Run(ctx, "nvidia-smi", "-L")
+	rec.Run(ctx, "nvidia-smi", "-L")
+	if result := rec.Run(ctx, python, "-c", "import vllm"); result.Err != importErr {
+		t.Fatalf("recorder changed the error: %v", result.Err)
+	}
+	var out bytes.Buffer
+	if err := rec.RunWithStreaming(ctx, &out, io.Discard, "uv", "pip", "install", "--python", python, "vllm"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "Resolved 42 packages\n" {
+		t.Fatalf("recorder did not pass output through: %q", out.String())
+	}
+	rec.LookPath("uv")
+	rec.LookPath("docker")
+	resp, err := (&http.Client{Transport: rec}).Get(modelURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	srv.Close()
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), home) {
+		t.Errorf("cassette contains the home directory %s", home)
+	}
+
+	// Replay as another user, with nothing runnable behind the replayer.
+	other := t.TempDir()
+	t.Setenv("HOME", other)
+	python = filepath.Join(other, ".cache", "hermes", "envs", "vllm", "bin", "python")
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep := NewReplayer(cassette)
+
+	// Repeated calls replay in order, then repeat the last recording.
+	for i, want := range []string{"GPU\n", "GPU\nGPU\n", "GPU\nGPU\n"} {
+		if result := rep.Run(ctx, "nvidia-smi", "-L"); result.Stdout != want || result.Err != nil {
+			t.Errorf("nvidia-smi -L call %d = %q, %v; want %q", i+1, result.Stdout, result.Err, want)
+		}
+	}
+
+	result := rep.Run(ctx, python, "-c", "import vllm")
+	if result.ExitCode != 1 || !errors.Is(result.Err, ErrExit) {
+		t.Errorf("import vllm = exit %d, %v; want exit 1 of kind %s", result.ExitCode, result.Err, KindExit)
+	}
+	// The recorded home directory is replaced by the replaying one.
+	if want := python + ": exit status 1: ModuleNotFoundError: No module named 'vllm'"; result.Err != nil && result.Err.Error() != want {
+		t.Errorf("import vllm error = %q, want %q", result.Err, want)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := rep.RunWithStreaming(ctx, &stdout, &stderr, "uv", "pip", "install", "--python", python, "vllm"); err != nil {
+		t.Errorf("uv pip install: %v", err)
+	}
+	if stdout.String() != "Resolved 42 packages\n" || stderr.String() != "Installed 42 packages\n" {
+		t.Errorf("uv pip install output = %q, %q", stdout.String(), stderr.String())
+	}
+
+	if got, err := rep.LookPath("uv"); err != nil || got != filepath.Join(other, ".local", "bin", "uv") {
+		t.Errorf("LookPath(uv) = %q, %v", got, err)
+	}
+	if _, err := rep.LookPath("docker"); KindOf(err) != KindNotFound {
+		t.Errorf("LookPath(docker) error = %v, want kind %s", err, KindNotFound)
+	}
+
+	resp, err = (&http.Client{Transport: rep}).Get(modelURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != `{"id":"Qwen/Qwen3-8B"}` {
+		t.Errorf("GET %s = %d %q", modelURL, resp.StatusCode, body)
+	}
+}
+
+func TestReplayerRejectsUnrecordedCalls(t *testing.T) {
+	rep := NewReplayer(&Cassette{Entries: []Entry{
+		{Kind: "run", Name: "nvidia-smi", Args: []string{"-L"}, Stdout: "GPU 0"},
+		{Kind: "stream", Name: "uv", Args: []string{"pip", "install", "vllm"}},
+		{Kind: "http", Name: "GET", Args: []string{"https://huggingface.co/api/models/a"}, ExitCode: 200},
+	}})
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		err  func() error
+	}{
+		{"different arguments", func() error { return rep.Run(ctx, "nvidia-smi", "-q").Err }},
+		{"different command", func() error { return rep.Run(ctx, "rocm-smi", "-L").Err }},
+		{"run recorded as stream", func() error { return rep.Run(ctx, "uv", "pip", "install", "vllm").Err }},
+		{"stream", func() error {
+			return rep.RunWithStreaming(ctx, io.Discard, io.Discard, "uv", "pip", "install", "sglang")
+		}},
+		{"http", func() error {
+			_, err := (&http.Client{Transport: rep}).Get("https://huggingface.co/api/models/b")
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.err(); !errors.Is(err, ErrNotRecorded) {
+				t.Errorf("error = %v, want %v", err, ErrNotRecorded)
+			}
+		})
+	}
+
+	if result := rep.Run(ctx, "nvidia-smi", "-q"); result.ExitCode != -1 {
+		t.Errorf("unrecorded command exit code = %d, want -1", result.ExitCode)
+	}
+	if Retryable(ctx, ErrNotRecorded) {
+		t.Error("ErrNotRecorded is retryable")
+	}
+}
@@ -0,0 +1,128 @@
+package execx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrorKind classifies why an external command failed.
+type ErrorKind string
+
+const (
+	KindNotFound ErrorKind = "not-found"
+	KindTimeout  ErrorKind = "timeout"
+	KindExit     ErrorKind = "exit"
+	KindSignal   ErrorKind = "signal"
+)
+
+// Sentinels for errors.Is; each matches any *Error of its kind.
+var (
+	ErrNotFound = errors.New("command not found")
+	ErrTimeout  = errors.New("command timed out")
+	ErrExit     = errors.New("command exited with non-zero status")
+	ErrSignaled = errors.New("command killed by signal")
+)
+
+// Error is the error a runner returns for a failed command.
+type Error struct {
+	Kind     ErrorKind
+	Command  string
+	ExitCode int
+	Signal   string
+	Timeout  time.Duration
+	// Stderr is the last line of the command's error output, if captured.
+	Stderr string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	var msg string
+	switch e.Kind {
+	case KindNotFound:
+		msg = fmt.Sprintf("%s: not found", e.Command)
+	case KindTimeout:
+		if e.Timeout > 0 {
+			msg = fmt.Sprintf("%s: timed out after %s", e.Command, e.Timeout)
+		} else {
+			msg = fmt.Sprintf("%s: timed out", e.Command)
+		}
+	case KindSignal:
+		msg = fmt.Sprintf("%s: killed by %s", e.Command, e.Signal)
+	default:
+		if e.ExitCode < 0 && e.Err != nil {
+			return fmt.Sprintf("%s: %v", e.Command, e.Err)
+		}
+		msg = fmt.Sprintf("%s: exit status %d", e.Command, e.ExitCode)
+	}
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Kind == KindNotFound
+	case ErrTimeout:
+		return e.Kind == KindTimeout
+	case ErrExit:
+		return e.Kind == KindExit
+	case ErrSignaled:
+		return e.Kind == KindSignal
+	}
+	return false
+}
+
+// KindOf returns the kind of a runner error, or "" for other errors.
+func KindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return ""
+}
+
+// classify turns the error from running name into an *Error. A command killed
+// because ctx's deadline passed is a timeout, not a signal.
+func classify(ctx context.Context, name string, err error, stderr string) error {
+	if err == nil {
+		return nil
+	}
+	e := &Error{Command: name, ExitCode: -1, Stderr: lastLine(stderr), Err: err}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(err, exec.ErrNotFound), errors.Is(err, syscall.ENOENT):
+		e.Kind, e.Stderr = KindNotFound, ""
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		e.Kind = KindTimeout
+		if deadline, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+			e.Timeout = deadline
+		}
+	case errors.As(err, &exitErr):
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			e.Kind, e.Signal = KindSignal, ws.Signal().String()
+		} else {
+			e.Kind, e.ExitCode = KindExit, exitErr.ExitCode()
+		}
+	default:
+		// Start failures other than a missing binary, such as EACCES.
+		e.Kind = KindExit
+	}
+	return e
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(s)
+}
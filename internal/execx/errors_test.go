@@ -0,0 +1,209 @@
+package execx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestLocalRunnerClassifiesFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		cmd      []string
+		kind     ErrorKind
+		exitCode int
+		msg      string
+	}{
+		{
+			name:     "missing binary",
+			cmd:      []string{"hermes-test-no-such-binary"},
+			kind:     KindNotFound,
+			exitCode: -1,
+			msg:      "hermes-test-no-such-binary: not found",
+		},
+		{
+			name:     "non-zero exit",
+			cmd:      []string{"sh", "-c", "echo first >&2; echo 'No module named vllm' >&2; exit 3"},
+			kind:     KindExit,
+			exitCode: 3,
+			msg:      "sh: exit status 3: No module named vllm",
+		},
+		{
+			name:     "timeout",
+			timeout:  50 * time.Millisecond,
+			cmd:      []string{"sleep", "5"},
+			kind:     KindTimeout,
+			exitCode: -1,
+			msg:      "sleep: timed out after 50ms",
+		},
+		{
+			name:     "signal",
+			cmd:      []string{"sh", "-c", "kill -TERM $$"},
+			kind:     KindSignal,
+			exitCode: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			result := LocalRunner{}.Run(ctx, tt.cmd[0], tt.cmd[1:]...)
+			if got := KindOf(result.Err); got != tt.kind {
+				t.Fatalf("kind = %q, want %q (%v)", got, tt.kind, result.Err)
+			}
+			if result.ExitCode != tt.exitCode {
+				t.Errorf("exit code = %d, want %d", result.ExitCode, tt.exitCode)
+			}
+			if tt.msg != "" && result.Err.Error() != tt.msg {
+				t.Errorf("error = %q, want %q", result.Err, tt.msg)
+			}
+		})
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	sentinels := map[ErrorKind]error{
+		KindNotFound: ErrNotFound,
+		KindTimeout:  ErrTimeout,
+		KindExit:     ErrExit,
+		KindSignal:   ErrSignaled,
+	}
+	for kind := range sentinels {
+		err := error(&Error{Kind: kind, Command: "nvidia-smi"})
+		for other, sentinel := range sentinels {
+			if got := errors.Is(err, sentinel); got != (other == kind) {
+				t.Errorf("errors.Is(%s error, %v) = %v", kind, sentinel, got)
+			}
+		}
+	}
+	if KindOf(errors.New("plain")) != "" {
+		t.Error("KindOf of a plain error is not empty")
+	}
+}
+
+func TestRunWith(t *testing.T) {
+	exitErr := &Error{Kind: KindExit, Command: "nvidia-smi", ExitCode: 1, Err: errors.New("exit status 1")}
+	notFound := &Error{Kind: KindNotFound, Command: "nvidia-smi", ExitCode: -1, Err: exec.ErrNotFound}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		cancel   bool
+		failures int
+		err      error
+		// hang makes the command wait for its context to end.
+		hang  bool
+		kind  ErrorKind
+		calls int
+	}{
+		{
+			name:  "success",
+			opts:  Options{Attempts: 3},
+			calls: 1,
+		},
+		{
+			name:     "zero attempts run once",
+			failures: 5,
+			err:      exitErr,
+			kind:     KindExit,
+			calls:    1,
+		},
+		{
+			name:     "non-zero exit retried until it succeeds",
+			opts:     Options{Attempts: 3, Backoff: time.Millisecond},
+			failures: 2,
+			err:      exitErr,
+			calls:    3,
+		},
+		{
+			name:     "non-zero exit retried until attempts run out",
+			opts:     Options{Attempts: 3, Backoff: time.Millisecond},
+			failures: 5,
+			err:      exitErr,
+			kind:     KindExit,
+			calls:    3,
+		},
+		{
+			name:     "missing binary not retried",
+			opts:     Options{Attempts: 3, Backoff: time.Millisecond},
+			failures: 5,
+			err:      notFound,
+			kind:     KindNotFound,
+			calls:    1,
+		},
+		{
+			name:  "timeout retried",
+			opts:  Options{Timeout: 10 * time.Millisecond, Attempts: 2, Backoff: time.Millisecond},
+			hang:  true,
+			kind:  KindTimeout,
+			calls: 2,
+		},
+		{
+			name:     "cancelled context not retried",
+			opts:     Options{Attempts: 3, Backoff: time.Millisecond},
+			cancel:   true,
+			failures: 5,
+			err:      exitErr,
+			kind:     KindExit,
+			calls:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			runner := &fakeRunner{run: func(ctx context.Context, cmd string) Result {
+				calls++
+				if tt.hang {
+					<-ctx.Done()
+					return Result{ExitCode: -1, Err: classify(ctx, "nvidia-smi", ctx.Err(), "")}
+				}
+				if calls <= tt.failures {
+					return Result{ExitCode: -1, Err: tt.err}
+				}
+				return Result{Stdout: "GPU 0"}
+			}}
+			ctx, cancel := context.WithCancel(WithRunner(context.Background(), runner))
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+
+			result := RunWith(ctx, tt.opts, "nvidia-smi", "-L")
+			if got := KindOf(result.Err); got != tt.kind {
+				t.Errorf("kind = %q, want %q (%v)", got, tt.kind, result.Err)
+			}
+			if calls != tt.calls {
+				t.Errorf("ran %d time(s), want %d", calls, tt.calls)
+			}
+			if tt.kind == "" && result.Stdout != "GPU 0" {
+				t.Errorf("stdout = %q", result.Stdout)
+			}
+		})
+	}
+}
+
+func TestRunWithReportsTimeout(t *testing.T) {
+	runner := &fakeRunner{run: func(ctx context.Context, cmd string) Result {
+		<-ctx.Done()
+		return Result{ExitCode: -1, Err: classify(ctx, "nvidia-smi", ctx.Err(), "")}
+	}}
+	ctx := WithRunner(context.Background(), runner)
+
+	result := RunWith(ctx, Options{Timeout: 20 * time.Millisecond}, "nvidia-smi")
+	if !errors.Is(result.Err, ErrTimeout) {
+		t.Fatalf("error = %v, want a timeout", result.Err)
+	}
+	if want := "nvidia-smi: timed out after 20ms"; result.Err.Error() != want {
+		t.Errorf("error = %q, want %q", result.Err, want)
+	}
+	if runner.count("nvidia-smi") != 1 {
+		t.Errorf("ran %d time(s), want 1", runner.count("nvidia-smi"))
+	}
+}
@@ -0,0 +1,189 @@
+package execx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+type readOnlyKey struct{}
+
+// ReadOnly marks ctx for commands that only inspect the host. A dry run
+// still executes them, so that its plan reflects the host's actual state.
+func ReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+func IsReadOnly(ctx context.Context) bool {
+	v, _ := ctx.Value(readOnlyKey{}).(bool)
+	return v
+}
+
+// Step is one action in a dry-run plan. Kind is "exec" for a command hermes
+// waits on, "launch" for a server process and "probe" for an HTTP check.
+type Step struct {
+	Kind       string   `json:"kind"`
+	Command    []string `json:"command,omitempty"`
+	Env        []string `json:"env,omitempty"`
+	Background bool     `json:"background,omitempty"`
+	LogFile    string   `json:"log_file,omitempty"`
+	Method     string   `json:"method,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	Body       string   `json:"body,omitempty"`
+	Note       string   `json:"note,omitempty"`
+}
+
+// Plan collects the steps of a dry run in order.
+type Plan struct {
+	mu    sync.Mutex
+	Steps []Step `json:"steps"`
+}
+
+func (p *Plan) Add(s Step) {
+	p.mu.Lock()
+	p.Steps = append(p.Steps, s)
+	p.mu.Unlock()
+}
+
+// Planner is a Runner that adds every command to a plan instead of running
+// it. Commands made with a ReadOnly context are passed to the inner runner.
+type Planner struct {
+	inner Runner
+	plan  *Plan
+}
+
+func NewPlanner(inner Runner) *Planner {
+	return &Planner{inner: inner, plan: &Plan{}}
+}
+
+func (p *Planner) Plan() *Plan {
+	return p.plan
+}
+
+func (p *Planner) Run(ctx context.Context, name string, args ...string) Result {
+	if IsReadOnly(ctx) {
+		return p.inner.Run(ctx, name, args...)
+	}
+	p.plan.Add(Step{Kind: "exec", Command: append([]string{name}, args...)})
+	return Result{}
+}
+
+func (p *Planner) RunWithStreaming(ctx context.Context, stdout, stderr io.Writer, name string, args ...string) error {
+	if IsReadOnly(ctx) {
+		return p.inner.RunWithStreaming(ctx, stdout, stderr, name, args...)
+	}
+	p.plan.Add(Step{Kind: "exec", Command: append([]string{name}, args...)})
+	return nil
+}
+
+func (p *Planner) CommandExists(name string) bool {
+	return p.inner.CommandExists(name)
+}
+
+func (p *Planner) LookPath(name string) (string, error) {
+	return p.inner.LookPath(name)
+}
+
+// WriteText prints the plan as a numbered list.
+func (p *Plan) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Plan (%d steps):\n", len(p.Steps))
+	for i, s := range p.Steps {
+		var line string
+		switch s.Kind {
+		case "probe":
+			line = s.Method + " " + s.URL
+			if s.Body != "" {
+				line += " " + s.Body
+			}
+		default:
+			line = strings.Join(append(append([]string{}, s.Env...), s.Command...), " ")
+			if s.Background {
+				line += " (background)"
+			}
+		}
+		if s.Note != "" {
+			line += "  # " + s.Note
+		}
+		fmt.Fprintf(w, "  %2d. %-6s %s\n", i+1, s.Kind, line)
+	}
+}
+
+func (p *Plan) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// WriteShell prints the plan as a POSIX shell script. Servers started in the
+// foreground are backgrounded so the probes after them can run, and waited
+// on at the end.
+func (p *Plan) WriteShell(w io.Writer) {
+	fmt.Fprintln(w, "#!/bin/sh")
+	fmt.Fprintln(w, "# Generated by hermes --dry-run")
+	fmt.Fprintln(w, "set -e")
+	foreground := false
+	for _, s := range p.Steps {
+		fmt.Fprintln(w)
+		if s.Note != "" {
+			fmt.Fprintln(w, "# "+s.Note)
+		}
+		switch s.Kind {
+		case "probe":
+			curl := []string{"curl", "-fsS", "-o", "/dev/null", "-X", s.Method}
+			if s.Body != "" {
+				curl = append(curl, "-H", "Content-Type: application/json", "-d", s.Body)
+			}
+			curl = append(curl, s.URL)
+			fmt.Fprintf(w, "until %s; do sleep 2; done\n", ShellJoin(curl))
+		case "launch":
+			cmd := ShellJoin(s.Command)
+			if len(s.Env) > 0 {
+				cmd = "env " + ShellJoin(s.Env) + " " + cmd
+			}
+			switch {
+			case s.Background && s.LogFile != "":
+				fmt.Fprintf(w, "nohup %s >> %s 2>&1 &\n", cmd, ShellQuote(s.LogFile))
+			case s.Background:
+				fmt.Fprintf(w, "nohup %s > /dev/null 2>&1 &\n", cmd)
+			default:
+				fmt.Fprintf(w, "%s &\n", cmd)
+				foreground = true
+			}
+		default:
+			fmt.Fprintln(w, ShellJoin(s.Command))
+		}
+	}
+	if foreground {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "wait")
+	}
+}
+
+// ShellJoin quotes args for a POSIX shell.
+func ShellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = ShellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=,+@%", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
@@ -3,6 +3,7 @@ package execx
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"os/exec"
 	"strings"
@@ -15,19 +16,33 @@ type Result struct {
 	Err      error
 }
 
-func Run(ctx context.Context, name string, args ...string) Result {
+// Runner executes external commands. Commands get theirs from the
+// AppContext; code that only has a context.Context reaches the same runner
+// through the package-level functions below.
+type Runner interface {
+	Run(ctx context.Context, name string, args ...string) Result
+	RunWithStreaming(ctx context.Context, stdout, stderr io.Writer, name string, args ...string) error
+	CommandExists(name string) bool
+	LookPath(name string) (string, error)
+}
+
+// LocalRunner runs commands on this machine.
+type LocalRunner struct{}
+
+func (LocalRunner) Run(ctx context.Context, name string, args ...string) Result {
 	cmd := exec.CommandContext(ctx, name, args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	cmd.WaitDelay = waitDelay
+
+	err := classify(ctx, name, cmd.Run(), stderr.String())
 
 	exitCode := 0
-	if exitErr, ok := err.(*exec.ExitError); ok {
-		exitCode = exitErr.ExitCode()
-	} else if err != nil {
-		exitCode = -1
+	var execErr *Error
+	if errors.As(err, &execErr) {
+		exitCode = execErr.ExitCode
 	}
 
 	return Result{
@@ -38,14 +53,54 @@ func Run(ctx context.Context, name string, args ...string) Result {
 	}
 }
 
-func RunWithStreaming(ctx context.Context, stdout, stderr io.Writer, name string, args ...string) error {
+func (LocalRunner) RunWithStreaming(ctx context.Context, stdout, stderr io.Writer, name string, args ...string) error {
 	cmd := exec.CommandContext(ctx, name, args...)
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
-	return cmd.Run()
+	cmd.WaitDelay = waitDelay
+	return classify(ctx, name, cmd.Run(), "")
 }
 
-func CommandExists(name string) bool {
+func (LocalRunner) CommandExists(name string) bool {
 	_, err := exec.LookPath(name)
 	return err == nil
 }
+
+func (LocalRunner) LookPath(name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", &Error{Kind: KindNotFound, Command: name, ExitCode: -1, Err: err}
+	}
+	return path, nil
+}
+
+type runnerKey struct{}
+
+// WithRunner returns a context whose package-level calls use r.
+func WithRunner(ctx context.Context, r Runner) context.Context {
+	return context.WithValue(ctx, runnerKey{}, r)
+}
+
+// From returns the runner carried by ctx, or a LocalRunner.
+func From(ctx context.Context) Runner {
+	if r, ok := ctx.Value(runnerKey{}).(Runner); ok {
+		return r
+	}
+	return LocalRunner{}
+}
+
+func Run(ctx context.Context, name string, args ...string) Result {
+	return From(ctx).Run(ctx, name, args...)
+}
+
+func RunWithStreaming(ctx context.Context, stdout, stderr io.Writer, name string, args ...string) error {
+	return From(ctx).RunWithStreaming(ctx, stdout, stderr, name, args...)
+}
+
+func CommandExists(ctx context.Context, name string) bool {
+	return From(ctx).CommandExists(name)
+}
+
+func LookPath(ctx context.Context, name string) (string, error) {
+	return From(ctx).LookPath(name)
+}
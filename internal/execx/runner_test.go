@@ -0,0 +1,60 @@
+package execx
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// fakeRunner answers commands with run, which gets the command line joined
+// by spaces, and resolves binaries from paths. It records every call.
+type fakeRunner struct {
+	mu    sync.Mutex
+	calls []string
+	run   func(ctx context.Context, cmd string) Result
+	paths map[string]string
+}
+
+func (f *fakeRunner) Run(ctx context.Context, name string, args ...string) Result {
+	cmd := strings.Join(append([]string{name}, args...), " ")
+	f.mu.Lock()
+	f.calls = append(f.calls, cmd)
+	f.mu.Unlock()
+	if f.run == nil {
+		return Result{}
+	}
+	return f.run(ctx, cmd)
+}
+
+func (f *fakeRunner) RunWithStreaming(ctx context.Context, stdout, stderr io.Writer, name string, args ...string) error {
+	result := f.Run(ctx, name, args...)
+	io.WriteString(stdout, result.Stdout)
+	io.WriteString(stderr, result.Stderr)
+	return result.Err
+}
+
+func (f *fakeRunner) CommandExists(name string) bool {
+	_, err := f.LookPath(name)
+	return err == nil
+}
+
+func (f *fakeRunner) LookPath(name string) (string, error) {
+	if path, ok := f.paths[name]; ok {
+		return path, nil
+	}
+	return "", &Error{Kind: KindNotFound, Command: name, ExitCode: -1, Err: exec.ErrNotFound}
+}
+
+func (f *fakeRunner) count(cmd string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, c := range f.calls {
+		if c == cmd {
+			n++
+		}
+	}
+	return n
+}
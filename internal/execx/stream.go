@@ -0,0 +1,224 @@
+package execx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+type StreamName string
+
+const (
+	Stdout StreamName = "stdout"
+	Stderr StreamName = "stderr"
+)
+
+// Line is one line of a command's output, without its newline.
+type Line struct {
+	Stream StreamName `json:"stream"`
+	Text   string     `json:"text"`
+	Time   time.Time  `json:"time"`
+}
+
+type LineFunc func(Line)
+
+const (
+	// DefaultMaxLineBytes splits longer lines so a command that never
+	// prints a newline (progress bars) cannot grow the buffer unbounded.
+	DefaultMaxLineBytes = 64 * 1024
+	DefaultTailLines    = 200
+)
+
+// LineWriter is an io.Writer that calls fn for every complete line written
+// to it. Carriage returns end a line too, so progress bars arrive as lines.
+type LineWriter struct {
+	mu      sync.Mutex
+	stream  StreamName
+	fn      LineFunc
+	maxLine int
+	buf     []byte
+}
+
+func NewLineWriter(stream StreamName, maxLine int, fn LineFunc) *LineWriter {
+	if maxLine <= 0 {
+		maxLine = DefaultMaxLineBytes
+	}
+	return &LineWriter{stream: stream, fn: fn, maxLine: maxLine}
+}
+
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		line := w.buf[:i]
+		for len(line) > w.maxLine {
+			w.emit(line[:w.maxLine])
+			line = line[w.maxLine:]
+		}
+		w.emit(line)
+		w.buf = w.buf[i+1:]
+	}
+	for len(w.buf) >= w.maxLine {
+		w.emit(w.buf[:w.maxLine])
+		w.buf = w.buf[w.maxLine:]
+	}
+	// Compact so the backing array does not keep consumed output alive.
+	w.buf = append([]byte(nil), w.buf...)
+	return len(p), nil
+}
+
+// Flush delivers a final line that had no newline.
+func (w *LineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *LineWriter) emit(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	w.fn(Line{Stream: w.stream, Text: string(b), Time: time.Now()})
+}
+
+// Tail is a ring buffer holding the last lines it was given.
+type Tail struct {
+	mu    sync.Mutex
+	lines []Line
+	next  int
+	full  bool
+}
+
+func NewTail(n int) *Tail {
+	if n <= 0 {
+		n = DefaultTailLines
+	}
+	return &Tail{lines: make([]Line, n)}
+}
+
+func (t *Tail) Add(l Line) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines[t.next] = l
+	t.next = (t.next + 1) % len(t.lines)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// Lines returns the buffered lines, oldest first.
+func (t *Tail) Lines() []Line {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.full {
+		return append([]Line(nil), t.lines[:t.next]...)
+	}
+	return append(append([]Line(nil), t.lines[t.next:]...), t.lines[:t.next]...)
+}
+
+// String joins the buffered lines' text.
+func (t *Tail) String() string {
+	lines := t.Lines()
+	text := make([]string, len(lines))
+	for i, l := range lines {
+		text[i] = l.Text
+	}
+	return strings.Join(text, "\n")
+}
+
+// StreamOptions configures Stream. Every field is optional.
+type StreamOptions struct {
+	// OnLine receives each line as it is produced.
+	OnLine LineFunc
+	// Output receives each line, formatted with Prefix and, if Timestamps
+	// is set, the time and stream.
+	Output     io.Writer
+	Prefix     string
+	Timestamps bool
+	// TailLines is how many lines to keep for StreamResult.Tail.
+	TailLines    int
+	MaxLineBytes int
+}
+
+type StreamResult struct {
+	Err   error
+	Lines int
+	Tail  *Tail
+}
+
+// FormatLine renders l the way Stream writes it to StreamOptions.Output.
+func FormatLine(l Line, prefix string, timestamps bool) string {
+	if timestamps {
+		return fmt.Sprintf("%s %s %s%s\n", l.Time.Format("2006-01-02T15:04:05.000"), l.Stream, prefix, l.Text)
+	}
+	return prefix + l.Text + "\n"
+}
+
+// LineSink fans each line out according to opts and records it in a tail.
+// It is the building block of Stream for callers that manage their own
+// exec.Cmd, such as long-running servers.
+type LineSink struct {
+	opts   StreamOptions
+	tail   *Tail
+	mu     sync.Mutex
+	lines  int
+	stdout *LineWriter
+	stderr *LineWriter
+}
+
+func NewLineSink(opts StreamOptions) *LineSink {
+	s := &LineSink{opts: opts, tail: NewTail(opts.TailLines)}
+	s.stdout = NewLineWriter(Stdout, opts.MaxLineBytes, s.handle)
+	s.stderr = NewLineWriter(Stderr, opts.MaxLineBytes, s.handle)
+	return s
+}
+
+func (s *LineSink) handle(l Line) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines++
+	s.tail.Add(l)
+	if s.opts.Output != nil {
+		io.WriteString(s.opts.Output, FormatLine(l, s.opts.Prefix, s.opts.Timestamps))
+	}
+	if s.opts.OnLine != nil {
+		s.opts.OnLine(l)
+	}
+}
+
+func (s *LineSink) Stdout() io.Writer { return s.stdout }
+func (s *LineSink) Stderr() io.Writer { return s.stderr }
+func (s *LineSink) Tail() *Tail       { return s.tail }
+
+// Close flushes partial lines.
+func (s *LineSink) Close() {
+	s.stdout.Flush()
+	s.stderr.Flush()
+}
+
+func (s *LineSink) Lines() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lines
+}
+
+// Stream runs a command through the context's runner, delivering its output
+// line by line. Only the last TailLines lines are retained.
+func Stream(ctx context.Context, opts StreamOptions, name string, args ...string) StreamResult {
+	sink := NewLineSink(opts)
+	err := RunWithStreaming(ctx, sink.Stdout(), sink.Stderr(), name, args...)
+	sink.Close()
+	return StreamResult{Err: err, Lines: sink.Lines(), Tail: sink.Tail()}
+}
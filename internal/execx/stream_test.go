@@ -0,0 +1,176 @@
+package execx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeLines feeds chunks to a LineWriter, flushes it, and returns the text
+// of every line it delivered.
+func writeLines(maxLine int, chunks ...string) []string {
+	var got []string
+	w := NewLineWriter(Stdout, maxLine, func(l Line) { got = append(got, l.Text) })
+	for _, c := range chunks {
+		w.Write([]byte(c))
+	}
+	w.Flush()
+	return got
+}
+
+func TestLineWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxLine int
+		chunks  []string
+		want    []string
+	}{
+		{
+			name:   "newlines",
+			chunks: []string{"one\ntwo\n"},
+			want:   []string{"one", "two"},
+		},
+		{
+			name:   "lines split across writes",
+			chunks: []string{"Resolv", "ed 42 pack", "ages\nInst", "alled\n"},
+			want:   []string{"Resolved 42 packages", "Installed"},
+		},
+		{
+			// tqdm and pip redraw their bar with a carriage return.
+			name:   "carriage-return progress",
+			chunks: []string{"Downloading  10%\rDownloading  55%\r", "Downloading 100%\n"},
+			want:   []string{"Downloading  10%", "Downloading  55%", "Downloading 100%"},
+		},
+		{
+			name:   "CRLF yields no empty lines",
+			chunks: []string{"one\r\ntwo\r\n\n\n"},
+			want:   []string{"one", "two"},
+		},
+		{
+			name:   "final line without newline",
+			chunks: []string{"done\nexit"},
+			want:   []string{"done", "exit"},
+		},
+		{
+			name:    "long line split at maxLine",
+			maxLine: 4,
+			chunks:  []string{"abcdefghij\n"},
+			want:    []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name:    "long line split across writes",
+			maxLine: 4,
+			chunks:  []string{"ab", "cde", "fghi", "j"},
+			want:    []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name:    "line of exactly maxLine",
+			maxLine: 4,
+			chunks:  []string{"abcd\nef\n"},
+			want:    []string{"abcd", "ef"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := writeLines(tt.maxLine, tt.chunks...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineWriterDefaultMaxLine(t *testing.T) {
+	got := writeLines(0, strings.Repeat("x", DefaultMaxLineBytes+1))
+	if len(got) != 2 || len(got[0]) != DefaultMaxLineBytes || got[1] != "x" {
+		t.Errorf("got %d line(s), first %d bytes", len(got), len(got[0]))
+	}
+}
+
+func tailText(tail *Tail) []string {
+	var text []string
+	for _, l := range tail.Lines() {
+		text = append(text, l.Text)
+	}
+	return text
+}
+
+func TestTail(t *testing.T) {
+	tests := []struct {
+		size  int
+		added int
+		want  []string
+	}{
+		{size: 3, added: 0, want: nil},
+		{size: 3, added: 2, want: []string{"1", "2"}},
+		{size: 3, added: 3, want: []string{"1", "2", "3"}},
+		{size: 3, added: 4, want: []string{"2", "3", "4"}},
+		{size: 3, added: 7, want: []string{"5", "6", "7"}},
+		{size: 1, added: 5, want: []string{"5"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d of %d", tt.added, tt.size), func(t *testing.T) {
+			tail := NewTail(tt.size)
+			for i := 1; i <= tt.added; i++ {
+				tail.Add(Line{Text: fmt.Sprint(i)})
+			}
+			if got := tailText(tail); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines() = %q, want %q", got, tt.want)
+			}
+			if got, want := tail.String(), strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("String() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestTailDefaultSize(t *testing.T) {
+	tail := NewTail(0)
+	for i := 0; i < DefaultTailLines+10; i++ {
+		tail.Add(Line{Text: fmt.Sprint(i)})
+	}
+	got := tail.Lines()
+	if len(got) != DefaultTailLines || got[0].Text != "10" {
+		t.Errorf("kept %d lines starting at %q", len(got), got[0].Text)
+	}
+}
+
+func TestStream(t *testing.T) {
+	runner := &fakeRunner{run: func(ctx context.Context, cmd string) Result {
+		return Result{
+			Stdout: "Resolved 3 packages\n 10%\r 60%\r100%\nInstalled 3 packages",
+			Stderr: "warning: cache miss\n",
+		}
+	}}
+	ctx := WithRunner(context.Background(), runner)
+
+	var onLine []Line
+	var out strings.Builder
+	result := Stream(ctx, StreamOptions{
+		OnLine:    func(l Line) { onLine = append(onLine, l) },
+		Output:    &out,
+		Prefix:    "[uv] ",
+		TailLines: 2,
+	}, "uv", "pip", "install", "vllm")
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	// The fake writes stdout before stderr; stdout's unterminated last line
+	// is only delivered when the stream closes.
+	if result.Lines != 6 || len(onLine) != 6 {
+		t.Fatalf("Lines = %d with %d delivered, want 6", result.Lines, len(onLine))
+	}
+	if got := onLine[4]; got.Stream != Stderr || got.Text != "warning: cache miss" {
+		t.Errorf("stderr line = %+v", got)
+	}
+	if got := onLine[5]; got.Stream != Stdout || got.Text != "Installed 3 packages" {
+		t.Errorf("last line = %+v", got)
+	}
+	if got, want := tailText(result.Tail), []string{"warning: cache miss", "Installed 3 packages"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tail = %q, want %q", got, want)
+	}
+	if want := "[uv] Resolved 3 packages\n[uv]  10%\n[uv]  60%\n[uv] 100%\n[uv] warning: cache miss\n[uv] Installed 3 packages\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,81 @@
+package execx
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// ProbeTimeout bounds quick host queries such as `nvidia-smi` or
+	// `uv --version`, which hang rather than fail when a driver is wedged.
+	ProbeTimeout = 15 * time.Second
+	// ImportTimeout bounds commands that import a Python package, which can
+	// take tens of seconds on a cold cache.
+	ImportTimeout = 90 * time.Second
+
+	// waitDelay is how long a killed command's output pipes may stay open,
+	// held by grandchildren, before Wait gives up on them.
+	waitDelay = 5 * time.Second
+)
+
+type timeoutKey struct{}
+
+// WithTimeout is context.WithTimeout, also recording d so that a command the
+// deadline kills reports how long it was given.
+func WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	return context.WithValue(ctx, timeoutKey{}, d), cancel
+}
+
+// Options bounds a call made with RunWith. Zero values mean no timeout and a
+// single attempt.
+type Options struct {
+	// Timeout applies to each attempt.
+	Timeout time.Duration
+	// Attempts is the total number of tries for a failing command.
+	Attempts int
+	// Backoff is the wait before the second attempt; it doubles after that.
+	Backoff time.Duration
+}
+
+// RunWith runs a command with a per-attempt timeout, retrying failures that
+// may be transient. A missing binary or a cancelled ctx is never retried.
+func RunWith(ctx context.Context, opts Options, name string, args ...string) Result {
+	backoff := opts.Backoff
+	var result Result
+	for attempt := 1; ; attempt++ {
+		result = runOnce(ctx, opts.Timeout, name, args...)
+		if result.Err == nil || attempt >= opts.Attempts || !Retryable(ctx, result.Err) {
+			return result
+		}
+		select {
+		case <-ctx.Done():
+			return result
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func runOnce(ctx context.Context, timeout time.Duration, name string, args ...string) Result {
+	if timeout <= 0 {
+		return Run(ctx, name, args...)
+	}
+	ctx, cancel := WithTimeout(ctx, timeout)
+	defer cancel()
+	return Run(ctx, name, args...)
+}
+
+// Retryable reports whether a command that failed with err is worth running
+// again under ctx.
+func Retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrNotRecorded) {
+		return false
+	}
+	switch KindOf(err) {
+	case KindTimeout, KindExit, KindSignal:
+		return true
+	}
+	return false
+}
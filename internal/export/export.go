@@ -0,0 +1,329 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/svngoku/hermes-cli/internal/engine"
+	"github.com/svngoku/hermes-cli/internal/execx"
+	"github.com/svngoku/hermes-cli/internal/gpu"
+)
+
+// Format is an artifact that `hermes export` can render.
+type Format string
+
+const (
+	Bash       Format = "bash"
+	Dockerfile Format = "dockerfile"
+	Compose    Format = "compose"
+)
+
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case Bash, Dockerfile, Compose:
+		return Format(name), nil
+	}
+	return "", fmt.Errorf("invalid format: %s (use bash, dockerfile or compose)", name)
+}
+
+// containerEnv is where the engine's virtualenv lives inside an image.
+const containerEnv = "/opt/hermes/env"
+
+// containerHFCache is where the image expects the Hugging Face cache.
+const containerHFCache = "/root/.cache/huggingface"
+
+// passthroughEnv is forwarded by name so tokens are never written out.
+var passthroughEnv = []string{"HF_TOKEN", "HUGGING_FACE_HUB_TOKEN"}
+
+// Spec is a resolved install and serve configuration. Command refers to
+// binaries by name; every artifact activates the virtualenv first.
+type Spec struct {
+	Engine         string
+	Model          string
+	Requirement    string
+	Python         string
+	ExtraIndexURLs []string
+	Command        []string
+	Env            []string
+	Host           string
+	Port           int
+	GPUs           string
+	ShmSize        string
+	BaseImage      string
+	// LocalModel is an absolute model directory that must be mounted.
+	LocalModel       string
+	Probes           []engine.Probe
+	ReadinessTimeout int
+	// Notes are emitted as comments at the top of the artifact.
+	Notes []string
+}
+
+func Render(w io.Writer, format Format, spec Spec) error {
+	switch format {
+	case Bash:
+		return renderBash(w, spec)
+	case Dockerfile:
+		return renderDockerfile(w, spec)
+	case Compose:
+		return renderCompose(w, spec)
+	}
+	return fmt.Errorf("invalid format: %s", format)
+}
+
+func header(w io.Writer, spec Spec) {
+	fmt.Fprintf(w, "# Generated by hermes export: %s serving %s\n", spec.Engine, spec.Model)
+	for _, note := range spec.Notes {
+		fmt.Fprintf(w, "# NOTE: %s\n", note)
+	}
+}
+
+// venvCommand is `uv venv` without its target directory.
+func (s Spec) venvCommand() []string {
+	args := []string{"uv", "venv"}
+	if s.Python != "" {
+		args = append(args, "--python", s.Python)
+	}
+	return args
+}
+
+// pipArgs follow `uv pip install --python <interpreter>`.
+func (s Spec) pipArgs() []string {
+	var args []string
+	for _, url := range s.ExtraIndexURLs {
+		args = append(args, "--extra-index-url", url)
+	}
+	return append(args, s.Requirement)
+}
+
+// localURL is the address the readiness checks use from the serving host.
+func (s Spec) localURL() string {
+	host := s.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return fmt.Sprintf("http://%s:%d", host, s.Port)
+}
+
+// healthProbe is the first probe that does not generate, for container
+// health checks that run repeatedly.
+func (s Spec) healthProbe() engine.Probe {
+	for _, p := range s.Probes {
+		if !p.Generation && p.Method == "GET" {
+			return p
+		}
+	}
+	return engine.DefaultProbes()[0]
+}
+
+const bashProbe = `probe() {
+  local out
+  if [ -n "$3" ]; then
+    out=$(curl -fsS -m 60 -X "$1" -H 'Content-Type: application/json' -d "$3" "$2" 2>/dev/null) || return 1
+  else
+    out=$(curl -fsS -m 10 -X "$1" "$2" 2>/dev/null) || return 1
+  fi
+  [ -z "$4" ] || printf '%s' "$out" | grep -qF -- "$4"
+}
+
+wait_for() {
+  local name=$1
+  shift
+  until probe "$@"; do
+    if ! kill -0 "$SERVER_PID" 2>/dev/null; then
+      echo "server exited before $name passed" >&2
+      exit 1
+    fi
+    if [ "$SECONDS" -ge "$DEADLINE" ]; then
+      echo "timed out waiting for $name" >&2
+      exit 1
+    fi
+    sleep 2
+  done
+  echo "ready: $name"
+}
+`
+
+func renderBash(w io.Writer, spec Spec) error {
+	fmt.Fprintln(w, "#!/usr/bin/env bash")
+	header(w, spec)
+	fmt.Fprintln(w, "set -euo pipefail")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "ENV_DIR=\"${HERMES_ENV_DIR:-$HOME/.cache/hermes/envs/%s}\"\n", spec.Engine)
+	fmt.Fprintf(w, "READINESS_TIMEOUT=\"${READINESS_TIMEOUT:-%d}\"\n", spec.ReadinessTimeout)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "if ! command -v uv >/dev/null 2>&1; then")
+	fmt.Fprintln(w, "  curl -LsSf https://astral.sh/uv/install.sh | sh")
+	fmt.Fprintln(w, "  export PATH=\"$HOME/.local/bin:$PATH\"")
+	fmt.Fprintln(w, "fi")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "if [ ! -x \"$ENV_DIR/bin/python\" ]; then")
+	fmt.Fprintf(w, "  %s \"$ENV_DIR\"\n", execx.ShellJoin(spec.venvCommand()))
+	fmt.Fprintln(w, "fi")
+	fmt.Fprintf(w, "uv pip install --python \"$ENV_DIR/bin/python\" %s\n", execx.ShellJoin(spec.pipArgs()))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "export VIRTUAL_ENV=\"$ENV_DIR\"")
+	fmt.Fprintln(w, "export PATH=\"$ENV_DIR/bin:$PATH\"")
+	fmt.Fprintf(w, "export %s\n", gpu.DeviceOrderEnv)
+	if spec.GPUs != "" {
+		fmt.Fprintf(w, "export CUDA_VISIBLE_DEVICES=%s\n", execx.ShellQuote(spec.GPUs))
+	}
+	for _, kv := range spec.Env {
+		fmt.Fprintf(w, "export %s\n", execx.ShellQuote(kv))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s &\n", execx.ShellJoin(spec.Command))
+	fmt.Fprintln(w, "SERVER_PID=$!")
+	fmt.Fprintln(w, "trap 'kill \"$SERVER_PID\" 2>/dev/null || true' INT TERM")
+	fmt.Fprintln(w)
+	if spec.ReadinessTimeout > 0 && len(spec.Probes) > 0 {
+		io.WriteString(w, bashProbe)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "DEADLINE=$((SECONDS + READINESS_TIMEOUT))")
+		base := spec.localURL()
+		for _, p := range spec.Probes {
+			fmt.Fprintf(w, "wait_for %s %s %s %s %s\n", execx.ShellQuote(p.Name), p.Method,
+				execx.ShellQuote(base+p.Path), execx.ShellQuote(p.Body), execx.ShellQuote(p.ExpectBody))
+		}
+		fmt.Fprintf(w, "echo \"Server ready at %s\"\n", base)
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "wait \"$SERVER_PID\"")
+	return nil
+}
+
+// dockerfile renders the image build. The server binds every interface
+// inside the container.
+func dockerfile(spec Spec) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "FROM %s\n", spec.BaseImage)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "RUN apt-get update \\")
+	fmt.Fprintln(&b, " && apt-get install -y --no-install-recommends ca-certificates curl \\")
+	fmt.Fprintln(&b, " && rm -rf /var/lib/apt/lists/*")
+	fmt.Fprintln(&b, "COPY --from=ghcr.io/astral-sh/uv:latest /uv /usr/local/bin/uv")
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "ENV VIRTUAL_ENV=%s \\\n    PATH=%s/bin:$PATH \\\n    %s\n", containerEnv, containerEnv, gpu.DeviceOrderEnv)
+	fmt.Fprintf(&b, "RUN %s %s \\\n && uv pip install --python %s/bin/python %s\n",
+		execx.ShellJoin(spec.venvCommand()), containerEnv, containerEnv, execx.ShellJoin(spec.pipArgs()))
+	if len(spec.Env) > 0 {
+		fmt.Fprintln(&b)
+		for _, kv := range spec.Env {
+			k, v, _ := strings.Cut(kv, "=")
+			fmt.Fprintf(&b, "ENV %s=%s\n", k, strconv.Quote(v))
+		}
+	}
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "EXPOSE %d\n", spec.Port)
+	probe := spec.healthProbe()
+	fmt.Fprintf(&b, "HEALTHCHECK --interval=10s --timeout=5s --start-period=%ds --retries=3 \\\n  CMD curl -fsS http://127.0.0.1:%d%s || exit 1\n",
+		spec.ReadinessTimeout, spec.Port, probe.Path)
+	fmt.Fprintf(&b, "CMD %s\n", jsonArray(spec.Command))
+	return b.String()
+}
+
+func renderDockerfile(w io.Writer, spec Spec) error {
+	header(w, spec)
+	fmt.Fprintf(w, "# Build: docker build -t hermes-%s .\n", spec.Engine)
+	fmt.Fprintf(w, "# Run:   %s\n", dockerRun(spec))
+	fmt.Fprintln(w)
+	_, err := io.WriteString(w, dockerfile(spec))
+	return err
+}
+
+func dockerRun(spec Spec) string {
+	args := []string{"docker", "run", "--rm"}
+	if spec.GPUs == "" {
+		args = append(args, "--gpus", "all")
+	} else {
+		args = append(args, "--gpus", strconv.Quote("device="+spec.GPUs))
+	}
+	args = append(args, "--publish", fmt.Sprintf("%d:%d", spec.Port, spec.Port))
+	if spec.ShmSize != "" {
+		args = append(args, "--shm-size", spec.ShmSize)
+	}
+	// Left unquoted by ShellJoin so that $HOME expands.
+	cache := fmt.Sprintf("--volume \"$HOME/.cache/huggingface:%s\"", containerHFCache)
+	var rest []string
+	if spec.LocalModel != "" {
+		rest = append(rest, "--volume", spec.LocalModel+":"+spec.LocalModel+":ro")
+	}
+	rest = append(rest, "--env", "HF_TOKEN", "hermes-"+spec.Engine)
+	return execx.ShellJoin(args) + " " + cache + " " + execx.ShellJoin(rest)
+}
+
+func renderCompose(w io.Writer, spec Spec) error {
+	header(w, spec)
+	fmt.Fprintln(w, "# Run: docker compose up -d")
+	fmt.Fprintln(w, "services:")
+	fmt.Fprintf(w, "  %s:\n", spec.Engine)
+	fmt.Fprintln(w, "    build:")
+	fmt.Fprintln(w, "      context: .")
+	fmt.Fprintln(w, "      dockerfile_inline: |")
+	// Compose interpolates variables everywhere; $$ keeps a literal $.
+	inline := strings.ReplaceAll(dockerfile(spec), "$", "$$")
+	for _, line := range strings.Split(strings.TrimRight(inline, "\n"), "\n") {
+		if line == "" {
+			fmt.Fprintln(w)
+			continue
+		}
+		fmt.Fprintf(w, "        %s\n", line)
+	}
+	fmt.Fprintf(w, "    image: hermes-%s\n", spec.Engine)
+
+	publish := fmt.Sprintf("%d:%d", spec.Port, spec.Port)
+	if spec.Host != "" && spec.Host != "0.0.0.0" {
+		publish = spec.Host + ":" + publish
+	}
+	fmt.Fprintln(w, "    ports:")
+	fmt.Fprintf(w, "      - %s\n", strconv.Quote(publish))
+	if spec.ShmSize != "" {
+		fmt.Fprintf(w, "    shm_size: %s\n", strconv.Quote(spec.ShmSize))
+	}
+	fmt.Fprintln(w, "    environment:")
+	for _, name := range passthroughEnv {
+		fmt.Fprintf(w, "      - %s\n", name)
+	}
+	fmt.Fprintln(w, "    volumes:")
+	fmt.Fprintf(w, "      - %s\n", strconv.Quote("${HF_HOME:-${HOME}/.cache/huggingface}:"+containerHFCache))
+	if spec.LocalModel != "" {
+		fmt.Fprintf(w, "      - %s\n", strconv.Quote(spec.LocalModel+":"+spec.LocalModel+":ro"))
+	}
+	fmt.Fprintln(w, "    deploy:")
+	fmt.Fprintln(w, "      resources:")
+	fmt.Fprintln(w, "        reservations:")
+	fmt.Fprintln(w, "          devices:")
+	fmt.Fprintln(w, "            - driver: nvidia")
+	if spec.GPUs == "" {
+		fmt.Fprintln(w, "              count: all")
+	} else {
+		var ids []string
+		for _, id := range strings.Split(spec.GPUs, ",") {
+			ids = append(ids, strconv.Quote(strings.TrimSpace(id)))
+		}
+		fmt.Fprintf(w, "              device_ids: [%s]\n", strings.Join(ids, ", "))
+	}
+	fmt.Fprintln(w, "              capabilities: [gpu]")
+
+	probe := spec.healthProbe()
+	test := []string{"CMD", "curl", "-fsS", fmt.Sprintf("http://127.0.0.1:%d%s", spec.Port, probe.Path)}
+	fmt.Fprintln(w, "    healthcheck:")
+	fmt.Fprintf(w, "      test: %s\n", jsonArray(test))
+	fmt.Fprintln(w, "      interval: 10s")
+	fmt.Fprintln(w, "      timeout: 5s")
+	fmt.Fprintln(w, "      retries: 3")
+	fmt.Fprintf(w, "      start_period: %ds\n", spec.ReadinessTimeout)
+	fmt.Fprintln(w, "    restart: unless-stopped")
+	return nil
+}
+
+// jsonArray renders args as a JSON array, which is also valid YAML and the
+// exec form of a Dockerfile CMD.
+func jsonArray(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = strconv.Quote(a)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
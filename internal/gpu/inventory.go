@@ -0,0 +1,263 @@
+package gpu
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/svngoku/hermes-cli/internal/execx"
+)
+
+// Inventory is the driver and per-GPU state reported by `nvidia-smi -q -x`.
+type Inventory struct {
+	DriverVersion string `json:"driver_version"`
+	// CUDAVersion is the newest CUDA version the driver supports.
+	CUDAVersion string `json:"cuda_version"`
+	GPUs        []GPU  `json:"gpus"`
+}
+
+// GPU is one device. Readings the driver reports as N/A are left zero; ECC
+// is nil when ECC is disabled or unsupported.
+type GPU struct {
+	Index             int        `json:"index"`
+	UUID              string     `json:"uuid"`
+	Name              string     `json:"name"`
+	PCIBusID          string     `json:"pci_bus_id"`
+	ComputeCapability string     `json:"compute_capability,omitempty"`
+	MemoryTotalMiB    int64      `json:"memory_total_mib"`
+	MemoryUsedMiB     int64      `json:"memory_used_mib"`
+	UtilizationPct    int        `json:"utilization_pct"`
+	TemperatureC      int        `json:"temperature_c"`
+	PowerDrawW        float64    `json:"power_draw_w,omitempty"`
+	PowerLimitW       float64    `json:"power_limit_w,omitempty"`
+	ComputeMode       string     `json:"compute_mode"`
+	MIGMode           string     `json:"mig_mode,omitempty"`
+	ECC               *ECCErrors `json:"ecc_errors,omitempty"`
+	Processes         []Process  `json:"processes,omitempty"`
+}
+
+// ECCErrors counts errors since the driver was loaded (volatile) and over
+// the device's lifetime (aggregate).
+type ECCErrors struct {
+	VolatileCorrected    int64 `json:"volatile_corrected"`
+	VolatileUncorrected  int64 `json:"volatile_uncorrected"`
+	AggregateCorrected   int64 `json:"aggregate_corrected"`
+	AggregateUncorrected int64 `json:"aggregate_uncorrected"`
+}
+
+// Process is a compute or graphics process holding GPU memory.
+type Process struct {
+	PID           int    `json:"pid"`
+	Type          string `json:"type"`
+	Name          string `json:"name"`
+	UsedMemoryMiB int64  `json:"used_memory_mib"`
+}
+
+// LoadInventory runs `nvidia-smi -q -x` and parses it. Compute capability is
+// not in the XML; it is queried separately, and left empty on drivers too
+// old to report it.
+func LoadInventory(ctx context.Context) (*Inventory, error) {
+	opts := execx.Options{Timeout: execx.ProbeTimeout}
+	ctx = execx.ReadOnly(ctx)
+	result := execx.RunWith(ctx, opts, "nvidia-smi", "-q", "-x")
+	if result.Err != nil {
+		return nil, fmt.Errorf("querying GPUs: %w", result.Err)
+	}
+	inv, err := ParseInventory([]byte(result.Stdout))
+	if err != nil {
+		return nil, err
+	}
+	caps := execx.RunWith(ctx, opts, "nvidia-smi", "--query-gpu=index,compute_cap", "--format=csv,noheader")
+	if caps.Err == nil {
+		inv.setComputeCapabilities(caps.Stdout)
+	}
+	return inv, nil
+}
+
+// The XML schema has changed between drivers: power readings moved from
+// power_readings to gpu_power_readings, and ECC counters from single/double
+// bit totals to per-memory (sram, dram) counters. Both are accepted.
+type smiLog struct {
+	DriverVersion string   `xml:"driver_version"`
+	CUDAVersion   string   `xml:"cuda_version"`
+	GPUs          []smiGPU `xml:"gpu"`
+}
+
+type smiGPU struct {
+	ID          string `xml:"id,attr"`
+	ProductName string `xml:"product_name"`
+	UUID        string `xml:"uuid"`
+	ComputeMode string `xml:"compute_mode"`
+	MIGMode     struct {
+		Current string `xml:"current_mig"`
+	} `xml:"mig_mode"`
+	PCI struct {
+		BusID string `xml:"pci_bus_id"`
+	} `xml:"pci"`
+	Memory struct {
+		Total string `xml:"total"`
+		Used  string `xml:"used"`
+	} `xml:"fb_memory_usage"`
+	Utilization struct {
+		GPU string `xml:"gpu_util"`
+	} `xml:"utilization"`
+	Temperature struct {
+		GPU string `xml:"gpu_temp"`
+	} `xml:"temperature"`
+	Power       smiPower `xml:"gpu_power_readings"`
+	LegacyPower smiPower `xml:"power_readings"`
+	ECCMode     struct {
+		Current string `xml:"current_ecc"`
+	} `xml:"ecc_mode"`
+	ECC struct {
+		Volatile  xmlNode `xml:"volatile"`
+		Aggregate xmlNode `xml:"aggregate"`
+	} `xml:"ecc_errors"`
+	Processes []struct {
+		PID        string `xml:"pid"`
+		Type       string `xml:"type"`
+		Name       string `xml:"process_name"`
+		UsedMemory string `xml:"used_memory"`
+	} `xml:"processes>process_info"`
+}
+
+type smiPower struct {
+	Draw         string `xml:"power_draw"`
+	Limit        string `xml:"power_limit"`
+	CurrentLimit string `xml:"current_power_limit"`
+}
+
+// xmlNode captures a subtree whose element names vary.
+type xmlNode struct {
+	XMLName xml.Name
+	Value   string    `xml:",chardata"`
+	Nodes   []xmlNode `xml:",any"`
+}
+
+// ParseInventory reads `nvidia-smi -q -x` output. GPUs are indexed in
+// document order, which is nvidia-smi's own numbering.
+func ParseInventory(data []byte) (*Inventory, error) {
+	var doc smiLog
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing nvidia-smi XML: %w", err)
+	}
+
+	inv := &Inventory{
+		DriverVersion: strings.TrimSpace(doc.DriverVersion),
+		CUDAVersion:   strings.TrimSpace(doc.CUDAVersion),
+	}
+	for i, g := range doc.GPUs {
+		power := g.Power
+		if power.Draw == "" {
+			power = g.LegacyPower
+		}
+		limit := power.CurrentLimit
+		if limit == "" {
+			limit = power.Limit
+		}
+		busID := strings.TrimSpace(g.PCI.BusID)
+		if busID == "" {
+			busID = g.ID
+		}
+		gpu := GPU{
+			Index:          i,
+			UUID:           strings.TrimSpace(g.UUID),
+			Name:           strings.TrimSpace(g.ProductName),
+			PCIBusID:       busID,
+			MemoryTotalMiB: int64(reading(g.Memory.Total)),
+			MemoryUsedMiB:  int64(reading(g.Memory.Used)),
+			UtilizationPct: int(reading(g.Utilization.GPU)),
+			TemperatureC:   int(reading(g.Temperature.GPU)),
+			PowerDrawW:     reading(power.Draw),
+			PowerLimitW:    reading(limit),
+			ComputeMode:    strings.TrimSpace(g.ComputeMode),
+			MIGMode:        notAvailable(g.MIGMode.Current),
+		}
+		if strings.EqualFold(strings.TrimSpace(g.ECCMode.Current), "Enabled") {
+			ecc := &ECCErrors{}
+			ecc.VolatileCorrected, ecc.VolatileUncorrected = eccCounts(g.ECC.Volatile)
+			ecc.AggregateCorrected, ecc.AggregateUncorrected = eccCounts(g.ECC.Aggregate)
+			gpu.ECC = ecc
+		}
+		for _, p := range g.Processes {
+			pid, _ := strconv.Atoi(strings.TrimSpace(p.PID))
+			gpu.Processes = append(gpu.Processes, Process{
+				PID:           pid,
+				Type:          strings.TrimSpace(p.Type),
+				Name:          strings.TrimSpace(p.Name),
+				UsedMemoryMiB: int64(reading(p.UsedMemory)),
+			})
+		}
+		inv.GPUs = append(inv.GPUs, gpu)
+	}
+	return inv, nil
+}
+
+// reading parses the number in a value such as "81559 MiB", "70.25 W" or
+// "33 C". N/A and other non-numeric values read as zero.
+func reading(s string) float64 {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// notAvailable blanks a value the driver does not report: N/A in the XML,
+// [N/A] or [Not Supported] in CSV output.
+func notAvailable(s string) string {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "N/A", "[N/A]", "[Not Supported]":
+		return ""
+	}
+	return s
+}
+
+// eccCounts sums a volatile or aggregate ECC section. Newer drivers report
+// counters named *_correctable and *_uncorrectable; older ones report
+// single_bit and double_bit groups with a total.
+func eccCounts(n xmlNode) (corrected, uncorrected int64) {
+	for _, c := range n.Nodes {
+		name := c.XMLName.Local
+		switch {
+		case name == "single_bit" || name == "double_bit":
+			for _, t := range c.Nodes {
+				if t.XMLName.Local != "total" {
+					continue
+				}
+				if name == "single_bit" {
+					corrected += int64(reading(t.Value))
+				} else {
+					uncorrected += int64(reading(t.Value))
+				}
+			}
+		case strings.HasSuffix(name, "_uncorrectable") || strings.Contains(name, "_uncorrectable_"):
+			uncorrected += int64(reading(c.Value))
+		case strings.HasSuffix(name, "_correctable"):
+			corrected += int64(reading(c.Value))
+		}
+	}
+	return corrected, uncorrected
+}
+
+// setComputeCapabilities merges `--query-gpu=index,compute_cap` output.
+func (inv *Inventory) setComputeCapabilities(csv string) {
+	for _, line := range strings.Split(csv, "\n") {
+		index, capability, ok := strings.Cut(line, ",")
+		if !ok {
+			continue
+		}
+		i, err := strconv.Atoi(strings.TrimSpace(index))
+		if err != nil || i < 0 || i >= len(inv.GPUs) {
+			continue
+		}
+		inv.GPUs[i].ComputeCapability = notAvailable(capability)
+	}
+}
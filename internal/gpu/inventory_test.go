@@ -0,0 +1,147 @@
+package gpu
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func loadFixture(t *testing.T, name string) *Inventory {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv, err := ParseInventory(data)
+	if err != nil {
+		t.Fatalf("ParseInventory(%s): %v", name, err)
+	}
+	return inv
+}
+
+func TestParseInventory(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    *Inventory
+	}{
+		{
+			// gpu_power_readings, per-memory sram/dram ECC counters, and a
+			// second GPU with ECC disabled and most readings N/A.
+			fixture: "h100-driver550.xml",
+			want: &Inventory{
+				DriverVersion: "550.54.15",
+				CUDAVersion:   "12.4",
+				GPUs: []GPU{
+					{
+						Index:          0,
+						UUID:           "GPU-6e7b1c0a-5a8d-3f11-9c2e-0d4b7a1e2f90",
+						Name:           "NVIDIA H100 80GB HBM3",
+						PCIBusID:       "00000000:18:00.0",
+						MemoryTotalMiB: 81559,
+						MemoryUsedMiB:  72410,
+						UtilizationPct: 87,
+						TemperatureC:   61,
+						PowerDrawW:     512.34,
+						PowerLimitW:    700,
+						ComputeMode:    "Default",
+						MIGMode:        "Disabled",
+						ECC: &ECCErrors{
+							VolatileCorrected:    5,
+							VolatileUncorrected:  1,
+							AggregateCorrected:   42,
+							AggregateUncorrected: 4,
+						},
+						Processes: []Process{
+							{PID: 48213, Type: "C", Name: "/opt/hermes/envs/vllm/bin/python3", UsedMemoryMiB: 71840},
+							{PID: 3127, Type: "G", Name: "/usr/lib/xorg/Xorg", UsedMemoryMiB: 18},
+						},
+					},
+					{
+						Index:          1,
+						UUID:           "GPU-0b52d9e4-7c1f-4a60-8e3d-51a2c6f8b7d3",
+						Name:           "NVIDIA H100 80GB HBM3",
+						PCIBusID:       "00000000:3B:00.0",
+						MemoryTotalMiB: 81559,
+						ComputeMode:    "Exclusive_Process",
+					},
+				},
+			},
+		},
+		{
+			// power_readings with power_limit, single_bit/double_bit totals,
+			// and MIG enabled with a process in a GPU instance.
+			fixture: "a100-driver470.xml",
+			want: &Inventory{
+				DriverVersion: "470.129.06",
+				CUDAVersion:   "11.4",
+				GPUs: []GPU{
+					{
+						Index:          0,
+						UUID:           "GPU-9d3a4f21-0c7e-11ec-8b6a-2f4e9a71c5d8",
+						Name:           "NVIDIA A100-SXM4-40GB",
+						PCIBusID:       "00000000:07:00.0",
+						MemoryTotalMiB: 40536,
+						MemoryUsedMiB:  9,
+						TemperatureC:   33,
+						PowerDrawW:     56.13,
+						PowerLimitW:    400,
+						ComputeMode:    "Default",
+						MIGMode:        "Enabled",
+						ECC: &ECCErrors{
+							VolatileCorrected:    4,
+							AggregateCorrected:   59,
+							AggregateUncorrected: 1,
+						},
+						Processes: []Process{
+							{PID: 20771, Type: "C", Name: "python", UsedMemoryMiB: 4864},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got := loadFixture(t, tt.fixture)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseInventory:\n got %+v\nwant %+v", got, tt.want)
+				for i := range min(len(got.GPUs), len(tt.want.GPUs)) {
+					if !reflect.DeepEqual(got.GPUs[i], tt.want.GPUs[i]) {
+						t.Errorf("GPU %d:\n got %+v (ECC %+v)\nwant %+v (ECC %+v)",
+							i, got.GPUs[i], got.GPUs[i].ECC, tt.want.GPUs[i], tt.want.GPUs[i].ECC)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseInventoryInvalid(t *testing.T) {
+	if _, err := ParseInventory([]byte("NVIDIA-SMI has failed because it couldn't communicate with the NVIDIA driver.")); err == nil {
+		t.Error("ParseInventory accepted non-XML output")
+	}
+}
+
+func TestSetComputeCapabilities(t *testing.T) {
+	inv := loadFixture(t, "h100-driver550.xml")
+	inv.setComputeCapabilities("0, 9.0\n1, [N/A]\n2, 8.0\n-1, 7.5\nnot a line\n")
+
+	if got := inv.GPUs[0].ComputeCapability; got != "9.0" {
+		t.Errorf("GPU 0 compute capability = %q, want 9.0", got)
+	}
+	if got := inv.GPUs[1].ComputeCapability; got != "" {
+		t.Errorf("GPU 1 compute capability = %q, want empty for N/A", got)
+	}
+	if len(inv.GPUs) != 2 {
+		t.Errorf("out-of-range indexes changed the GPU list: %d GPUs", len(inv.GPUs))
+	}
+}
+
+func TestSetComputeCapabilitiesNotSupported(t *testing.T) {
+	inv := loadFixture(t, "a100-driver470.xml")
+	inv.setComputeCapabilities("0, [Not Supported]\n")
+	if got := inv.GPUs[0].ComputeCapability; got != "" {
+		t.Errorf("compute capability = %q, want empty for [Not Supported]", got)
+	}
+}
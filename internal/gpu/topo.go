@@ -0,0 +1,345 @@
+package gpu
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/svngoku/hermes-cli/internal/execx"
+)
+
+// DeviceOrderEnv makes CUDA number GPUs by PCI bus ID, as nvidia-smi does.
+// CUDA otherwise puts the fastest device first, and on mixed machines the
+// indexes hermes reports and picks would name different GPUs.
+const DeviceOrderEnv = "CUDA_DEVICE_ORDER=PCI_BUS_ID"
+
+// Topology is the GPU section of `nvidia-smi topo -m`.
+type Topology struct {
+	GPUs []TopoGPU `json:"gpus"`
+}
+
+// TopoGPU is one GPU row of the topology matrix. CPUs and NUMA are empty
+// when the driver reports N/A, as on single-socket hosts. Links holds the
+// connection to each other GPU by index, as nvidia-smi names it: NV<n> for
+// n bonded NVLinks, then PIX, PXB, PHB, NODE and SYS from nearest to
+// farthest over PCIe.
+type TopoGPU struct {
+	Index int            `json:"index"`
+	CPUs  string         `json:"cpu_affinity,omitempty"`
+	NUMA  string         `json:"numa_affinity,omitempty"`
+	Links map[int]string `json:"links,omitempty"`
+}
+
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// LoadTopology runs `nvidia-smi topo -m` and parses its matrix.
+func LoadTopology(ctx context.Context) (*Topology, error) {
+	result := execx.RunWith(execx.ReadOnly(ctx), execx.Options{Timeout: execx.ProbeTimeout}, "nvidia-smi", "topo", "-m")
+	if result.Err != nil {
+		return nil, fmt.Errorf("reading GPU topology: %w", result.Err)
+	}
+	return ParseTopology(result.Stdout)
+}
+
+// ParseTopology reads the rows of the matrix whose label is GPU<n>. Columns
+// are located by header name because NIC columns vary between hosts.
+func ParseTopology(out string) (*Topology, error) {
+	lines := strings.Split(ansiRe.ReplaceAllString(out, ""), "\n")
+	cpuCol, numaCol := -1, -1
+	gpuCols := make(map[int]int)
+	topo := &Topology{}
+	for _, line := range lines {
+		fields := splitTabs(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if cpuCol < 0 {
+			// The header's first cell, above the row labels, is empty; it
+			// is lost when the output has been trimmed.
+			if fields[0] != "" {
+				fields = append([]string{""}, fields...)
+			}
+			for i, f := range fields {
+				switch f {
+				case "CPU Affinity":
+					cpuCol = i
+				case "NUMA Affinity":
+					numaCol = i
+				default:
+					if n, ok := gpuLabel(f); ok {
+						gpuCols[i] = n
+					}
+				}
+			}
+			continue
+		}
+		index, ok := gpuLabel(fields[0])
+		if !ok {
+			continue
+		}
+		row := TopoGPU{Index: index, Links: make(map[int]string)}
+		for col, peer := range gpuCols {
+			if col < len(fields) && peer != index {
+				row.Links[peer] = fields[col]
+			}
+		}
+		if cpuCol < len(fields) {
+			row.CPUs = affinityValue(fields[cpuCol])
+		}
+		if numaCol >= 0 && numaCol < len(fields) {
+			row.NUMA = affinityValue(fields[numaCol])
+		}
+		topo.GPUs = append(topo.GPUs, row)
+	}
+	if cpuCol < 0 {
+		return nil, fmt.Errorf("no CPU Affinity column in nvidia-smi topo output")
+	}
+	return topo, nil
+}
+
+// gpuLabel reads a GPU<n> row or column label.
+func gpuLabel(s string) (int, bool) {
+	if !strings.HasPrefix(s, "GPU") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "GPU"))
+	return n, err == nil
+}
+
+// splitTabs splits a matrix line into trimmed cells.
+func splitTabs(line string) []string {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	fields := strings.Split(line, "\t")
+	for i, f := range fields {
+		fields[i] = strings.TrimSpace(f)
+	}
+	return fields
+}
+
+func affinityValue(s string) string {
+	if s == "N/A" {
+		return ""
+	}
+	return s
+}
+
+// Affinity returns the union of the CPU and NUMA affinity of the given GPU
+// indexes, or of every GPU when indexes is empty, as cpulist strings.
+func (t *Topology) Affinity(indexes []int) (cpus, nodes string) {
+	want := make(map[int]bool)
+	for _, i := range indexes {
+		want[i] = true
+	}
+	var cpuSet, nodeSet []int
+	for _, g := range t.GPUs {
+		if len(want) > 0 && !want[g.Index] {
+			continue
+		}
+		cpuSet = append(cpuSet, ParseCPUList(g.CPUs)...)
+		nodeSet = append(nodeSet, ParseCPUList(g.NUMA)...)
+	}
+	return FormatCPUList(cpuSet), FormatCPUList(nodeSet)
+}
+
+// ParseCPUList expands a Linux cpulist such as "0-3,8,10-11". Malformed
+// elements are skipped.
+func ParseCPUList(s string) []int {
+	var ids []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			continue
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(hi); err != nil || end < start {
+				continue
+			}
+		}
+		for i := start; i <= end; i++ {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+// FormatCPUList renders ids as a sorted, de-duplicated cpulist.
+func FormatCPUList(ids []int) string {
+	if len(ids) == 0 {
+		return ""
+	}
+	sorted := append([]int(nil), ids...)
+	sort.Ints(sorted)
+	var parts []string
+	start, prev := sorted[0], sorted[0]
+	flush := func() {
+		if start == prev {
+			parts = append(parts, strconv.Itoa(start))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", start, prev))
+		}
+	}
+	for _, id := range sorted[1:] {
+		switch {
+		case id == prev:
+		case id == prev+1:
+			prev = id
+		default:
+			flush()
+			start, prev = id, id
+		}
+	}
+	flush()
+	return strings.Join(parts, ",")
+}
+
+// LinkRank orders connection types from slowest (SYS) to fastest, with more
+// bonded NVLinks ranking higher. Unknown types rank zero.
+func LinkRank(link string) int {
+	switch link {
+	case "SYS":
+		return 1
+	case "NODE":
+		return 2
+	case "PHB":
+		return 3
+	case "PXB":
+		return 4
+	case "PIX":
+		return 5
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(link, "NV")); err == nil && strings.HasPrefix(link, "NV") {
+		return 10 + n
+	}
+	return 0
+}
+
+func (t *Topology) gpu(index int) *TopoGPU {
+	for i := range t.GPUs {
+		if t.GPUs[i].Index == index {
+			return &t.GPUs[i]
+		}
+	}
+	return nil
+}
+
+// Link returns how GPUs a and b are connected, or "" if unknown.
+func (t *Topology) Link(a, b int) string {
+	if g := t.gpu(a); g != nil {
+		return g.Links[b]
+	}
+	return ""
+}
+
+// NVLinkIslands groups GPUs that reach each other over NVLink, directly or
+// through other members. GPUs without NVLink are left out.
+func (t *Topology) NVLinkIslands() [][]int {
+	seen := make(map[int]bool)
+	var islands [][]int
+	for _, g := range t.GPUs {
+		if seen[g.Index] {
+			continue
+		}
+		island := []int{}
+		queue := []int{g.Index}
+		seen[g.Index] = true
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			island = append(island, cur)
+			for peer, link := range t.gpu(cur).Links {
+				if strings.HasPrefix(link, "NV") && !seen[peer] && t.gpu(peer) != nil {
+					seen[peer] = true
+					queue = append(queue, peer)
+				}
+			}
+		}
+		if len(island) > 1 {
+			sort.Ints(island)
+			islands = append(islands, island)
+		}
+	}
+	return islands
+}
+
+// WeakestLink returns the slowest connection between any two GPUs of group.
+func (t *Topology) WeakestLink(group []int) string {
+	weakest := ""
+	for i, a := range group {
+		for _, b := range group[i+1:] {
+			link := t.Link(a, b)
+			if weakest == "" || LinkRank(link) < LinkRank(weakest) {
+				weakest = link
+			}
+		}
+	}
+	return weakest
+}
+
+// BestGroup picks tp GPUs from candidates for a tensor-parallel group. The
+// group whose slowest link is fastest wins, since every all-reduce waits on
+// it; ties go to the faster links overall, then to fewer NUMA nodes, then
+// to lower indexes. Every combination is scored, which is cheap for the at
+// most 16 GPUs of a node.
+func (t *Topology) BestGroup(tp int, candidates []int) ([]int, error) {
+	if tp < 1 || tp > len(candidates) {
+		return nil, fmt.Errorf("cannot pick %d GPU(s) from %d", tp, len(candidates))
+	}
+	sorted := append([]int(nil), candidates...)
+	sort.Ints(sorted)
+
+	type score struct{ weakest, total, numa int }
+	better := func(a, b score) bool {
+		if a.weakest != b.weakest {
+			return a.weakest > b.weakest
+		}
+		if a.total != b.total {
+			return a.total > b.total
+		}
+		return a.numa < b.numa
+	}
+	scoreOf := func(group []int) score {
+		s := score{weakest: LinkRank(t.WeakestLink(group))}
+		nodes := make(map[string]bool)
+		for i, a := range group {
+			for _, b := range group[i+1:] {
+				s.total += LinkRank(t.Link(a, b))
+			}
+			if g := t.gpu(a); g != nil {
+				nodes[g.NUMA] = true
+			}
+		}
+		s.numa = len(nodes)
+		return s
+	}
+
+	var best []int
+	var bestScore score
+	group := make([]int, 0, tp)
+	var choose func(start int)
+	choose = func(start int) {
+		if len(group) == tp {
+			if s := scoreOf(group); best == nil || better(s, bestScore) {
+				best, bestScore = append([]int(nil), group...), s
+			}
+			return
+		}
+		for i := start; i <= len(sorted)-(tp-len(group)); i++ {
+			group = append(group, sorted[i])
+			choose(i + 1)
+			group = group[:len(group)-1]
+		}
+	}
+	choose(0)
+	return best, nil
+}
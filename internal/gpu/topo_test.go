@@ -0,0 +1,175 @@
+package gpu
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadTopology(t *testing.T, name string) *Topology {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	topo, err := ParseTopology(string(data))
+	if err != nil {
+		t.Fatalf("ParseTopology(%s): %v", name, err)
+	}
+	return topo
+}
+
+func TestParseTopology(t *testing.T) {
+	topo := loadTopology(t, "topo-pcie-4gpu.txt")
+	want := &Topology{GPUs: []TopoGPU{
+		{Index: 0, CPUs: "0-23,48-71", NUMA: "0", Links: map[int]string{1: "PIX", 2: "SYS", 3: "SYS"}},
+		{Index: 1, CPUs: "0-23,48-71", NUMA: "0", Links: map[int]string{0: "PIX", 2: "SYS", 3: "SYS"}},
+		{Index: 2, CPUs: "24-47,72-95", NUMA: "1", Links: map[int]string{0: "SYS", 1: "SYS", 3: "PIX"}},
+		{Index: 3, CPUs: "24-47,72-95", NUMA: "1", Links: map[int]string{0: "SYS", 1: "SYS", 2: "PIX"}},
+	}}
+	if !reflect.DeepEqual(topo, want) {
+		t.Errorf("ParseTopology:\n got %+v\nwant %+v", topo, want)
+	}
+}
+
+func TestParseTopologyTrimmed(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "topo-nvlink-pairs-8gpu.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Captures pasted into issues lose the header's leading tab.
+	trimmed, err := ParseTopology(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := loadTopology(t, "topo-nvlink-pairs-8gpu.txt"); !reflect.DeepEqual(trimmed, want) {
+		t.Errorf("trimmed output parsed as\n%+v\nwant\n%+v", trimmed, want)
+	}
+}
+
+func TestParseTopologySingleSocket(t *testing.T) {
+	out := "\tGPU0\tCPU Affinity\tNUMA Affinity\tGPU NUMA ID\nGPU0\t X \t0-15\tN/A\t\tN/A\n"
+	topo, err := ParseTopology(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Topology{GPUs: []TopoGPU{{Index: 0, CPUs: "0-15", Links: map[int]string{}}}}
+	if !reflect.DeepEqual(topo, want) {
+		t.Errorf("ParseTopology:\n got %+v\nwant %+v", topo, want)
+	}
+}
+
+func TestParseTopologyInvalid(t *testing.T) {
+	if _, err := ParseTopology("Failed to initialize NVML: Driver/library version mismatch\n"); err == nil {
+		t.Error("ParseTopology accepted output without a matrix")
+	}
+}
+
+func TestNVLinkIslands(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    [][]int
+	}{
+		{"topo-pcie-4gpu.txt", nil},
+		{"topo-nvlink-pairs-8gpu.txt", [][]int{{0, 1}, {2, 3}, {4, 5}, {6, 7}}},
+	}
+	for _, tt := range tests {
+		if got := loadTopology(t, tt.fixture).NVLinkIslands(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: NVLinkIslands() = %v, want %v", tt.fixture, got, tt.want)
+		}
+	}
+}
+
+func TestBestGroup(t *testing.T) {
+	all := func(n int) []int {
+		ids := make([]int, n)
+		for i := range ids {
+			ids[i] = i
+		}
+		return ids
+	}
+	tests := []struct {
+		fixture    string
+		tp         int
+		candidates []int
+		want       []int
+	}{
+		// A PCIe switch pair beats crossing sockets.
+		{"topo-pcie-4gpu.txt", 2, all(4), []int{0, 1}},
+		{"topo-pcie-4gpu.txt", 2, []int{1, 2, 3}, []int{2, 3}},
+		// Every triple crosses sockets; ties go to lower indexes.
+		{"topo-pcie-4gpu.txt", 3, all(4), []int{0, 1, 2}},
+		{"topo-pcie-4gpu.txt", 4, all(4), []int{0, 1, 2, 3}},
+		// An NVLink pair beats PCIe within a socket.
+		{"topo-nvlink-pairs-8gpu.txt", 2, all(8), []int{0, 1}},
+		{"topo-nvlink-pairs-8gpu.txt", 2, []int{1, 2, 3, 5}, []int{2, 3}},
+		{"topo-nvlink-pairs-8gpu.txt", 2, []int{1, 2, 5}, []int{1, 2}},
+		// Two pairs on one socket beat pairs on different sockets.
+		{"topo-nvlink-pairs-8gpu.txt", 4, all(8), []int{0, 1, 2, 3}},
+		{"topo-nvlink-pairs-8gpu.txt", 4, []int{1, 2, 3, 4, 5, 6, 7}, []int{4, 5, 6, 7}},
+		{"topo-nvlink-pairs-8gpu.txt", 8, all(8), all(8)},
+	}
+	for _, tt := range tests {
+		got, err := loadTopology(t, tt.fixture).BestGroup(tt.tp, tt.candidates)
+		if err != nil {
+			t.Errorf("%s: BestGroup(%d, %v): %v", tt.fixture, tt.tp, tt.candidates, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: BestGroup(%d, %v) = %v, want %v", tt.fixture, tt.tp, tt.candidates, got, tt.want)
+		}
+	}
+}
+
+func TestBestGroupTooLarge(t *testing.T) {
+	topo := loadTopology(t, "topo-pcie-4gpu.txt")
+	for _, tp := range []int{0, 5} {
+		if group, err := topo.BestGroup(tp, []int{0, 1, 2, 3}); err == nil {
+			t.Errorf("BestGroup(%d) of 4 GPUs = %v, want an error", tp, group)
+		}
+	}
+}
+
+func TestAffinity(t *testing.T) {
+	topo := loadTopology(t, "topo-nvlink-pairs-8gpu.txt")
+	tests := []struct {
+		indexes     []int
+		cpus, nodes string
+	}{
+		{[]int{2, 3}, "0-31,64-95", "0"},
+		{[]int{6}, "32-63,96-127", "1"},
+		{[]int{3, 4}, "0-127", "0-1"},
+		{nil, "0-127", "0-1"},
+	}
+	for _, tt := range tests {
+		cpus, nodes := topo.Affinity(tt.indexes)
+		if cpus != tt.cpus || nodes != tt.nodes {
+			t.Errorf("Affinity(%v) = %q, %q; want %q, %q", tt.indexes, cpus, nodes, tt.cpus, tt.nodes)
+		}
+	}
+}
+
+func TestCPUList(t *testing.T) {
+	tests := []struct {
+		in   string
+		ids  []int
+		want string
+	}{
+		{"0-3,8,10-11", []int{0, 1, 2, 3, 8, 10, 11}, "0-3,8,10-11"},
+		{"5", []int{5}, "5"},
+		{"", nil, ""},
+		// Unsorted, overlapping and malformed elements are normalised.
+		{"8,0-2,1,x,7-5, 3", []int{8, 0, 1, 2, 1, 3}, "0-3,8"},
+	}
+	for _, tt := range tests {
+		ids := ParseCPUList(tt.in)
+		if !reflect.DeepEqual(ids, tt.ids) {
+			t.Errorf("ParseCPUList(%q) = %v, want %v", tt.in, ids, tt.ids)
+		}
+		if got := FormatCPUList(ids); got != tt.want {
+			t.Errorf("FormatCPUList(ParseCPUList(%q)) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
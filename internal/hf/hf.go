@@ -0,0 +1,218 @@
+package hf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Home returns HF_HOME, or ~/.cache/huggingface.
+func Home() string {
+	if dir := os.Getenv("HF_HOME"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cache", "huggingface")
+}
+
+// HubCache returns the directory models are downloaded to.
+func HubCache() string {
+	for _, name := range []string{"HF_HUB_CACHE", "HUGGINGFACE_HUB_CACHE"} {
+		if dir := os.Getenv(name); dir != "" {
+			return dir
+		}
+	}
+	return filepath.Join(Home(), "hub")
+}
+
+// Endpoint returns HF_ENDPOINT, or the public hub.
+func Endpoint() string {
+	if e := os.Getenv("HF_ENDPOINT"); e != "" {
+		return strings.TrimRight(e, "/")
+	}
+	return "https://huggingface.co"
+}
+
+func token() string {
+	for _, name := range []string{"HF_TOKEN", "HUGGING_FACE_HUB_TOKEN"} {
+		if t := os.Getenv(name); t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// IsRepoID reports whether model names a hub repository rather than a local
+// directory.
+func IsRepoID(model string) bool {
+	if filepath.IsAbs(model) || strings.HasPrefix(model, ".") {
+		return false
+	}
+	if _, err := os.Stat(model); err == nil {
+		return false
+	}
+	return strings.Count(model, "/") == 1
+}
+
+// CachedModel is one model repository in the hub cache.
+type CachedModel struct {
+	Repo  string `json:"repo"`
+	Bytes int64  `json:"bytes"`
+}
+
+// CachedModels lists the models in the hub cache dir, largest first. Sizes
+// count each blob once; snapshots only link to them.
+func CachedModels(dir string) ([]CachedModel, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var models []CachedModel
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), "models--") {
+			continue
+		}
+		repo := strings.ReplaceAll(strings.TrimPrefix(e.Name(), "models--"), "--", "/")
+		models = append(models, CachedModel{Repo: repo, Bytes: dirSize(filepath.Join(dir, e.Name(), "blobs"))})
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Bytes > models[j].Bytes })
+	return models, nil
+}
+
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// RepoFile is a file in a hub repository. BlobID names the file in the
+// cache's blobs directory: the LFS sha256 for large files, the git blob id
+// otherwise.
+type RepoFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	BlobID string `json:"blob_id"`
+}
+
+// RepoFiles lists the files at the head of repo's main branch, asking the hub
+// with client.
+func RepoFiles(ctx context.Context, client *http.Client, repo string) ([]RepoFile, error) {
+	u := fmt.Sprintf("%s/api/models/%s/revision/main?blobs=true", Endpoint(), (&url.URL{Path: repo}).EscapedPath())
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if t := token(); t != "" {
+		req.Header.Set("Authorization", "Bearer "+t)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("%s is gated or private (set HF_TOKEN)", repo)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%s not found on %s", repo, Endpoint())
+	default:
+		return nil, fmt.Errorf("listing %s: %s", repo, resp.Status)
+	}
+
+	var info struct {
+		Siblings []struct {
+			Name   string `json:"rfilename"`
+			Size   int64  `json:"size"`
+			BlobID string `json:"blobId"`
+			LFS    *struct {
+				SHA256 string `json:"sha256"`
+				Size   int64  `json:"size"`
+			} `json:"lfs"`
+		} `json:"siblings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("reading %s file list: %w", repo, err)
+	}
+	files := make([]RepoFile, 0, len(info.Siblings))
+	for _, s := range info.Siblings {
+		f := RepoFile{Path: s.Name, Size: s.Size, BlobID: s.BlobID}
+		if s.LFS != nil {
+			f.Size, f.BlobID = s.LFS.Size, s.LFS.SHA256
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// weightFormats are the checkpoint formats a repository may carry side by
+// side; serving engines load safetensors when present and skip the others.
+var weightFormats = []string{".safetensors", ".bin", ".pt", ".pth", ".ckpt", ".h5", ".msgpack", ".onnx", ".gguf"}
+
+func weightFormat(path string) string {
+	for _, ext := range weightFormats {
+		if strings.HasSuffix(path, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// Download is the estimated size of fetching a model into the cache.
+type Download struct {
+	Files  int   `json:"files"`
+	Total  int64 `json:"total_bytes"`
+	Cached int64 `json:"cached_bytes"`
+	Needed int64 `json:"needed_bytes"`
+}
+
+// EstimateDownload works out how much of repo an engine will download into
+// the hub cache dir: the safetensors weights (or PyTorch .bin files when
+// there are none) and the small config and tokenizer files, less the blobs
+// already cached.
+func EstimateDownload(ctx context.Context, client *http.Client, dir, repo string) (Download, error) {
+	files, err := RepoFiles(ctx, client, repo)
+	if err != nil {
+		return Download{}, err
+	}
+	want := ".bin"
+	for _, f := range files {
+		if weightFormat(f.Path) == ".safetensors" {
+			want = ".safetensors"
+			break
+		}
+	}
+
+	blobs := filepath.Join(dir, "models--"+strings.ReplaceAll(repo, "/", "--"), "blobs")
+	var d Download
+	for _, f := range files {
+		if format := weightFormat(f.Path); format != "" && format != want {
+			continue
+		}
+		d.Files++
+		d.Total += f.Size
+		if info, err := os.Stat(filepath.Join(blobs, f.BlobID)); err == nil && info.Size() == f.Size {
+			d.Cached += f.Size
+		}
+	}
+	d.Needed = d.Total - d.Cached
+	return d, nil
+}
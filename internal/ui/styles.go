@@ -1,6 +1,11 @@
 package ui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"io"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 var (
 	TitleStyle = lipgloss.NewStyle().
@@ -60,3 +65,14 @@ func Info(msg string) string {
 func Step(msg string) string {
 	return BoldStyle.Render("→ ") + msg
 }
+
+// IsTerminal reports whether w is an interactive terminal, which decides
+// between live TUI components and plain line output.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -26,11 +26,13 @@ const (
 )
 
 type StepsModel struct {
+	title       string
 	steps       []Step
 	currentStep int
 	spinner     spinner.Model
 	quitting    bool
 	done        bool
+	transient   bool
 	width       int
 }
 
@@ -45,6 +47,7 @@ func NewStepsModel(steps []string) StepsModel {
 	}
 
 	return StepsModel{
+		title:       "Hermes Pipeline",
 		steps:       stepList,
 		currentStep: 0,
 		spinner:     s,
@@ -52,6 +55,19 @@ func NewStepsModel(steps []string) StepsModel {
 	}
 }
 
+// WithTitle replaces the default heading.
+func (m StepsModel) WithTitle(title string) StepsModel {
+	m.title = title
+	return m
+}
+
+// Transient clears the list when the program ends, for callers that print
+// their own summary afterwards.
+func (m StepsModel) Transient() StepsModel {
+	m.transient = true
+	return m
+}
+
 func (m StepsModel) Init() tea.Cmd {
 	return m.spinner.Tick
 }
@@ -62,6 +78,14 @@ type StepCompleteMsg struct {
 	Detail string
 }
 
+// StepUpdateMsg changes a step's status and detail without advancing to the
+// next step, for tasks whose phases may be revisited or skipped.
+type StepUpdateMsg struct {
+	Index  int
+	Status StepStatus
+	Detail string
+}
+
 type AllDoneMsg struct{}
 
 func (m StepsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -80,7 +104,7 @@ func (m StepsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.spinner, cmd = m.spinner.Update(msg)
 		return m, cmd
 	case StepCompleteMsg:
-		if msg.Index < len(m.steps) {
+		if msg.Index >= 0 && msg.Index < len(m.steps) {
 			m.steps[msg.Index].Status = msg.Status
 			m.steps[msg.Index].Detail = msg.Detail
 			if msg.Index < len(m.steps)-1 {
@@ -89,6 +113,13 @@ func (m StepsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 		return m, nil
+	case StepUpdateMsg:
+		if msg.Index >= 0 && msg.Index < len(m.steps) {
+			m.steps[msg.Index].Status = msg.Status
+			m.steps[msg.Index].Detail = msg.Detail
+			m.currentStep = msg.Index
+		}
+		return m, nil
 	case AllDoneMsg:
 		m.done = true
 		return m, tea.Quit
@@ -122,9 +153,13 @@ var (
 )
 
 func (m StepsModel) View() string {
+	if m.transient && (m.done || m.quitting) {
+		return ""
+	}
+
 	var b strings.Builder
 
-	b.WriteString(titleStyle.Render("Hermes Pipeline"))
+	b.WriteString(titleStyle.Render(m.title))
 	b.WriteString("\n\n")
 
 	for i, step := range m.steps {
@@ -0,0 +1,26 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestStepsModelIgnoresOutOfRangeIndexes(t *testing.T) {
+	msgs := []tea.Msg{
+		StepUpdateMsg{Index: -1, Status: StepDone},
+		StepUpdateMsg{Index: 2, Status: StepDone},
+		StepCompleteMsg{Index: -1, Status: StepDone},
+		StepCompleteMsg{Index: 2, Status: StepDone},
+	}
+	for _, msg := range msgs {
+		m := NewStepsModel([]string{"a", "b"})
+		before := append([]Step(nil), m.steps...)
+		updated, _ := m.Update(msg)
+		for i, s := range updated.(StepsModel).steps {
+			if s.Status != before[i].Status {
+				t.Errorf("%#v changed step %d to %v", msg, i, s.Status)
+			}
+		}
+	}
+}